@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Lineage records, for every blink, how many copies of the target stone a
+// single copy of each stone present at that blink will eventually produce.
+// Stones that never lead to the target are dropped, so the layers stay as
+// compact as the count maps that Blink works with.
+type Lineage struct {
+	Target        string
+	Blinks        int
+	Copies        uint64
+	Contributions []StoneCount
+
+	rules         StoneRules
+	initialCounts map[string]uint64
+	yields        []map[string]uint64
+}
+
+func TraceLineage(initialCounts map[string]uint64, rules StoneRules, blinks int, target string) *Lineage {
+	layers := []map[string]uint64{initialCounts}
+	for blink := 0; blink < blinks; blink++ {
+		layers = append(layers, Blink(layers[blink], rules))
+	}
+
+	yields := make([]map[string]uint64, blinks+1)
+	yields[blinks] = make(map[string]uint64)
+	if _, ok := layers[blinks][target]; ok {
+		yields[blinks][target] = 1
+	}
+
+	for blink := blinks - 1; blink >= 0; blink-- {
+		yields[blink] = make(map[string]uint64)
+		for stone := range layers[blink] {
+			_, newStones := rules.Apply(stone)
+
+			var yield uint64 = 0
+			for _, newStone := range newStones {
+				yield += yields[blink+1][newStone]
+			}
+
+			if yield > 0 {
+				yields[blink][stone] = yield
+			}
+		}
+	}
+
+	lineage := &Lineage{
+		Target:        target,
+		Blinks:        blinks,
+		Contributions: make([]StoneCount, 0),
+		rules:         rules,
+		initialCounts: initialCounts,
+		yields:        yields,
+	}
+
+	for stone, yield := range yields[0] {
+		copies := initialCounts[stone] * yield
+		lineage.Copies += copies
+		lineage.Contributions = append(lineage.Contributions, StoneCount{Stone: stone, Count: copies})
+	}
+
+	sort.Slice(lineage.Contributions, func(i, j int) bool {
+		if lineage.Contributions[i].Count != lineage.Contributions[j].Count {
+			return lineage.Contributions[i].Count > lineage.Contributions[j].Count
+		}
+		return IsLargerStone(lineage.Contributions[j].Stone, lineage.Contributions[i].Stone)
+	})
+
+	return lineage
+}
+
+type lineageEdge struct {
+	rule         Rule
+	stone        string
+	multiplicity int
+}
+
+// children returns the stones one blink later that still lead to the target,
+// along with the rule that produced them and how many times each appears.
+func (l *Lineage) children(blink int, stone string) []lineageEdge {
+	if blink >= l.Blinks {
+		return nil
+	}
+
+	rule, newStones := l.rules.Apply(stone)
+
+	edges := make([]lineageEdge, 0, len(newStones))
+	for _, newStone := range newStones {
+		if l.yields[blink+1][newStone] == 0 {
+			continue
+		}
+
+		if len(edges) > 0 && edges[len(edges)-1].stone == newStone {
+			edges[len(edges)-1].multiplicity++
+			continue
+		}
+
+		edges = append(edges, lineageEdge{rule: rule, stone: newStone, multiplicity: 1})
+	}
+
+	return edges
+}
+
+func (l *Lineage) Write(w io.Writer, format string) error {
+	switch format {
+	case "tree":
+		return l.WriteTree(w)
+	case "dot":
+		return l.WriteDot(w)
+	default:
+		return fmt.Errorf("unknown trace format %q, expected tree or dot", format)
+	}
+}
+
+func (l *Lineage) WriteTree(w io.Writer) error {
+	_, err := fmt.Fprintf(w, "Stone %s after %d blink(s): %d copies\n", l.Target, l.Blinks, l.Copies)
+	if err != nil {
+		return err
+	}
+
+	written := make(map[string]bool)
+	for _, contribution := range l.Contributions {
+		_, err := fmt.Fprintf(w, "%s x%d contributes %d\n", contribution.Stone, l.initialCounts[contribution.Stone], contribution.Count)
+		if err != nil {
+			return err
+		}
+
+		err = l.writeSubtree(w, 0, contribution.Stone, 1, written)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (l *Lineage) writeSubtree(w io.Writer, blink int, stone string, depth int, written map[string]bool) error {
+	written[lineageNodeId(blink, stone)] = true
+
+	for _, edge := range l.children(blink, stone) {
+		id := lineageNodeId(blink+1, edge.stone)
+
+		line := fmt.Sprintf("%s--%s--> %s", strings.Repeat("  ", depth), edge.rule, edge.stone)
+		if edge.multiplicity > 1 {
+			line += fmt.Sprintf(" x%d", edge.multiplicity)
+		}
+		line += fmt.Sprintf(" (blink %d, yields %d)", blink+1, l.yields[blink+1][edge.stone])
+
+		if written[id] {
+			line += " (see above)"
+		}
+
+		if _, err := fmt.Fprintln(w, line); err != nil {
+			return err
+		}
+
+		if !written[id] {
+			err := l.writeSubtree(w, blink+1, edge.stone, depth+1, written)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (l *Lineage) WriteDot(w io.Writer) error {
+	lines := []string{"digraph lineage {", "  rankdir=TB;"}
+
+	type lineageNode struct {
+		blink int
+		stone string
+	}
+
+	visited := make(map[string]bool)
+	queue := make([]lineageNode, 0)
+	for _, contribution := range l.Contributions {
+		visited[lineageNodeId(0, contribution.Stone)] = true
+		queue = append(queue, lineageNode{blink: 0, stone: contribution.Stone})
+	}
+
+	for len(queue) > 0 {
+		blink, stone := queue[0].blink, queue[0].stone
+		queue = queue[1:]
+
+		id := lineageNodeId(blink, stone)
+
+		label := fmt.Sprintf("%s\\nblink %d\\nyields %d", stone, blink, l.yields[blink][stone])
+		if blink == 0 {
+			label += fmt.Sprintf("\\nx%d initially", l.initialCounts[stone])
+		}
+		lines = append(lines, fmt.Sprintf("  %q [label=\"%s\"];", id, label))
+
+		for _, edge := range l.children(blink, stone) {
+			childId := lineageNodeId(blink+1, edge.stone)
+
+			edgeLabel := string(edge.rule)
+			if edge.multiplicity > 1 {
+				edgeLabel += fmt.Sprintf(" x%d", edge.multiplicity)
+			}
+			lines = append(lines, fmt.Sprintf("  %q -> %q [label=%q];", id, childId, edgeLabel))
+
+			if !visited[childId] {
+				visited[childId] = true
+				queue = append(queue, lineageNode{blink: blink + 1, stone: edge.stone})
+			}
+		}
+	}
+
+	lines = append(lines, "}")
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+func lineageNodeId(blink int, stone string) string {
+	return fmt.Sprintf("%d:%s", blink, stone)
+}
@@ -1,34 +1,261 @@
 package main
 
 import (
+	"bufio"
+	"errors"
 	"flag"
 	"fmt"
+	"math/big"
+	"math/bits"
+	"os"
 	"strconv"
 	"strings"
 )
 
+type Settings struct {
+	Rules       StoneRules
+	Blinks      int
+	Report      string
+	TopK        int
+	Trace       string
+	TraceFormat string
+	Workers     int
+	Memo        string
+}
+
+func ParseInputFile(path string) []string {
+	var file *os.File
+	if path == "-" {
+		file = os.Stdin
+	} else {
+		var err error
+		file, err = os.Open(path)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		defer file.Close()
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+	return lines
+}
+
+func ParseStones(input string, rules StoneRules) ([]string, error) {
+	stones := strings.Fields(input)
+	for i, stone := range stones {
+		var err error
+		stones[i], err = rules.ParseStone(stone)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return stones, nil
+}
+
 func main() {
-	var blinks int
-	flag.IntVar(&blinks, "blinks", 1, "The number of times to blink")
+	var settings Settings
+	flag.IntVar(&settings.Blinks, "blinks", 1, "The number of times to blink")
 
 	var input string
 	flag.StringVar(&input, "input", "125 17", "The input array of stones, as a single string")
 
+	var path string
+	flag.StringVar(&path, "path", "", "The path to the input file, or - to read from stdin")
+
+	var batch bool
+	flag.BoolVar(&batch, "batch", false, "Treat each line of the input as a separate array of stones")
+
+	flag.StringVar(&settings.Report, "report", "", "Emit a per-blink evolution report in the given format (csv or json)")
+	flag.IntVar(&settings.TopK, "top", 5, "The number of most common stones to include in each report entry")
+	flag.StringVar(&settings.Trace, "trace", "", "Trace which initial stones the given final stone descends from")
+	flag.StringVar(&settings.TraceFormat, "trace-format", "tree", "The format of the lineage trace (tree or dot)")
+	flag.IntVar(&settings.Workers, "workers", 1, "The number of goroutines to shard each blink across")
+	flag.StringVar(&settings.Memo, "memo", "", "Count stones with the memoised counter, loading and saving its table at the given path")
+
+	var search string
+	flag.StringVar(&search, "search", "", "Rank every single-stone seed in the range from-to, written in -base, by the number of stones it produces")
+
+	var rank string
+	flag.StringVar(&rank, "rank", "most", "Whether the seed search ranks seeds producing the most or fewest stones first")
+
+	var base int
+	flag.IntVar(&base, "base", 10, "The numeric base (2 to 36) the stones are written in")
+
 	flag.Parse()
-	fmt.Println("Blinking ", blinks, " time(s) for the stone array ", input)
 
-	stones := strings.Split(input, " ")
+	if settings.TopK < 0 {
+		fmt.Println(fmt.Errorf("invalid -top %d, it cannot be negative", settings.TopK))
+		panic(0)
+	}
+
+	rules, err := NewStoneRules(base)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+	settings.Rules = rules
+
+	if settings.Trace != "" {
+		settings.Trace, err = rules.ParseStone(settings.Trace)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+
+	if search != "" {
+		SearchSeeds(search, rank, settings)
+		return
+	}
+
+	lines := []string{input}
+	if path != "" {
+		lines = ParseInputFile(path)
+	}
+	if !batch {
+		lines = []string{strings.Join(lines, " ")}
+	}
+
+	var stoneCounter *StoneCounter
+	if settings.Memo != "" {
+		stoneCounter, err = LoadStoneCounter(settings.Memo, settings.Rules)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+
+	for lineNumber, line := range lines {
+		stones, err := ParseStones(line, settings.Rules)
+		if err != nil {
+			fmt.Println(fmt.Errorf("line %d: %w", lineNumber+1, err))
+			panic(0)
+		}
+
+		if len(stones) == 0 {
+			continue
+		}
+
+		ProcessStones(stones, settings, stoneCounter)
+	}
+
+	if stoneCounter != nil {
+		err := stoneCounter.Save(settings.Memo)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+}
+
+func SearchSeeds(search string, rank string, settings Settings) {
+	from, to, err := ParseSeedRange(search, settings.Rules)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	if rank != "most" && rank != "fewest" {
+		fmt.Println(fmt.Errorf("unknown rank %q, expected most or fewest", rank))
+		panic(0)
+	}
+
+	stoneCounter := NewStoneCounter(settings.Rules)
+	if settings.Memo != "" {
+		stoneCounter, err = LoadStoneCounter(settings.Memo, settings.Rules)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+
+	rankings, err := RankSeeds(stoneCounter, from, to, settings.Blinks, rank == "fewest", settings.Workers, settings.TopK)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	fmt.Printf("Seeds from %s to %s producing the %s stones after %d blink(s):\n",
+		settings.Rules.Format(from), settings.Rules.Format(to), rank, settings.Blinks)
+	for i, ranking := range rankings {
+		fmt.Printf("%d. %s -> %d\n", i+1, ranking.Stone, ranking.Count)
+	}
+
+	if settings.Memo != "" {
+		err = stoneCounter.Save(settings.Memo)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+}
+
+func ProcessStones(stones []string, settings Settings, stoneCounter *StoneCounter) {
+	if settings.Report == "" && settings.Trace == "" {
+		fmt.Println("Blinking ", settings.Blinks, " time(s) for the stone array ", strings.Join(stones, " "))
+	}
+
 	stoneCounts := make(map[string]uint64)
 
 	for _, stone := range stones {
 		AddOrIncrementStoneCount(stoneCounts, stone, 1)
 	}
 
-	for blink := 0; blink < blinks; blink++ {
-		stoneCounts = Blink(stoneCounts)
+	if settings.Trace != "" {
+		lineage := TraceLineage(stoneCounts, settings.Rules, settings.Blinks, settings.Trace)
+		err := lineage.Write(os.Stdout, settings.TraceFormat)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		return
+	}
+
+	if stoneCounter != nil {
+		fmt.Println("Total number of resulting stones: ", stoneCounter.CountAll(stoneCounts, settings.Blinks))
+		return
+	}
+
+	var reports []BlinkReport
+	if settings.Report != "" {
+		reports = append(reports, NewBlinkReport(0, stoneCounts, settings.TopK))
+	}
+
+	// The stones stay partitioned between blinks, so the partitions are only
+	// joined when a report needs them all at once.
+	partitions := PartitionStones(stoneCounts, max(settings.Workers, 1))
+	for blink := 0; blink < settings.Blinks; blink++ {
+		partitions = BlinkPartitions(partitions, settings.Rules)
+
+		if settings.Report != "" {
+			reports = append(reports, NewBlinkReport(blink+1, JoinPartitions(partitions), settings.TopK))
+		}
 	}
 
-	fmt.Println("Total number of resulting stones: ", GetTotalStoneCount(stoneCounts))
+	if settings.Report != "" {
+		err := WriteReport(os.Stdout, settings.Report, reports, settings.TopK)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		return
+	}
+
+	var totalStoneCount uint64
+	for _, partition := range partitions {
+		totalStoneCount += GetTotalStoneCount(partition)
+	}
+	fmt.Println("Total number of resulting stones: ", totalStoneCount)
 }
 
 func AddOrIncrementStoneCount(stoneCounts map[string]uint64, stone string, increment uint64) {
@@ -40,32 +267,97 @@ func AddOrIncrementStoneCount(stoneCounts map[string]uint64, stone string, incre
 	}
 }
 
-func Blink(stoneCounts map[string]uint64) map[string]uint64 {
-	newStoneCounts := make(map[string]uint64)
-	for stone, count := range stoneCounts {
-		if stone == "0" {
-			AddOrIncrementStoneCount(newStoneCounts, "1", count)
-		} else if len(stone)%2 == 0 {
-			leftStone := strings.TrimLeft(stone[0:len(stone)/2], "0")
-			if leftStone == "" {
-				leftStone = "0"
-			}
-
-			rightStone := strings.TrimLeft(stone[len(stone)/2:], "0")
-			if rightStone == "" {
-				rightStone = "0"
-			}
-
-			AddOrIncrementStoneCount(newStoneCounts, leftStone, count)
-			AddOrIncrementStoneCount(newStoneCounts, rightStone, count)
+type Rule string
+
+const (
+	ZeroBecomesOne  Rule = "zero-becomes-one"
+	SplitEvenDigits Rule = "split-even-digits"
+	MultiplyBy2024  Rule = "multiply-by-2024"
+)
+
+type StoneRules struct {
+	Base int
+}
+
+func NewStoneRules(base int) (StoneRules, error) {
+	if base < 2 || base > 36 {
+		return StoneRules{}, fmt.Errorf("invalid base %d, expected a base from 2 to 36", base)
+	}
+
+	return StoneRules{Base: base}, nil
+}
+
+func (sr StoneRules) Apply(stone string) (Rule, []string) {
+	if stone == "0" {
+		return ZeroBecomesOne, []string{"1"}
+	} else if len(stone)%2 == 0 {
+		leftStone := strings.TrimLeft(stone[0:len(stone)/2], "0")
+		if leftStone == "" {
+			leftStone = "0"
+		}
+
+		rightStone := strings.TrimLeft(stone[len(stone)/2:], "0")
+		if rightStone == "" {
+			rightStone = "0"
+		}
+
+		return SplitEvenDigits, []string{leftStone, rightStone}
+	} else {
+		return MultiplyBy2024, []string{sr.Multiply(stone, 2024)}
+	}
+}
+
+func (sr StoneRules) Multiply(stone string, multiplier uint64) string {
+	stoneUint64, err := strconv.ParseUint(stone, sr.Base, 64)
+	if err == nil {
+		high, low := bits.Mul64(stoneUint64, multiplier)
+		if high == 0 {
+			return strconv.FormatUint(low, sr.Base)
+		}
+	} else if !errors.Is(err, strconv.ErrRange) {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	stoneInt, _ := new(big.Int).SetString(stone, sr.Base)
+	return stoneInt.Mul(stoneInt, new(big.Int).SetUint64(multiplier)).Text(sr.Base)
+}
+
+// Format writes the number as a stone in the rules' base.
+func (sr StoneRules) Format(number uint64) string {
+	return strconv.FormatUint(number, sr.Base)
+}
+
+func (sr StoneRules) ParseStone(stone string) (string, error) {
+	stone = strings.ToLower(stone)
+	for _, digit := range stone {
+		var value int
+		if digit >= '0' && digit <= '9' {
+			value = int(digit - '0')
+		} else if digit >= 'a' && digit <= 'z' {
+			value = int(digit-'a') + 10
 		} else {
-			stoneuint64, err := strconv.Atoi(stone)
-			if err != nil {
-				fmt.Println(err)
-				panic(0)
-			}
+			value = sr.Base
+		}
+
+		if value >= sr.Base {
+			return "", fmt.Errorf("invalid stone %q: stones must be non-negative integers in base %d", stone, sr.Base)
+		}
+	}
+
+	stone = strings.TrimLeft(stone, "0")
+	if stone == "" {
+		stone = "0"
+	}
 
-			newStone := strings.TrimLeft(strconv.Itoa(stoneuint64*2024), "0")
+	return stone, nil
+}
+
+func Blink(stoneCounts map[string]uint64, rules StoneRules) map[string]uint64 {
+	newStoneCounts := make(map[string]uint64)
+	for stone, count := range stoneCounts {
+		_, newStones := rules.Apply(stone)
+		for _, newStone := range newStones {
 			AddOrIncrementStoneCount(newStoneCounts, newStone, count)
 		}
 	}
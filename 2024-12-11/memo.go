@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+const memoMagic = "STONEMEMO"
+const memoVersion = 2
+
+// maxMemoStoneLength bounds the stones read from a memo table, so a corrupt
+// length cannot ask for an enormous allocation. Stones split long before they
+// get anywhere near it.
+const maxMemoStoneLength = 4096
+
+type memoKey struct {
+	stone  string
+	blinks int
+}
+
+// StoneCounter counts the stones a single stone turns into by recursing on
+// its rules, memoising the (stone, blinks remaining) pairs it sees. The memo
+// table can be shared between goroutines and persisted between runs.
+type StoneCounter struct {
+	Rules StoneRules
+
+	mutex sync.RWMutex
+	memo  map[memoKey]uint64
+
+	// maxStoneLength stops longer stones being memoised, with 0 meaning no
+	// limit. They are still counted, just not remembered.
+	maxStoneLength int
+}
+
+func NewStoneCounter(rules StoneRules) *StoneCounter {
+	return &StoneCounter{
+		Rules: rules,
+		memo:  make(map[memoKey]uint64),
+	}
+}
+
+func LoadStoneCounter(path string, rules StoneRules) (*StoneCounter, error) {
+	sc := NewStoneCounter(rules)
+
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return sc, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	_, err = sc.ReadFrom(file)
+	if err != nil {
+		return nil, fmt.Errorf("reading memo table %s: %w", path, err)
+	}
+
+	return sc, nil
+}
+
+func (sc *StoneCounter) Count(stone string, blinksRemaining int) uint64 {
+	if blinksRemaining <= 0 {
+		return 1
+	}
+
+	key := memoKey{stone: stone, blinks: blinksRemaining}
+
+	sc.mutex.RLock()
+	count, ok := sc.memo[key]
+	sc.mutex.RUnlock()
+	if ok {
+		return count
+	}
+
+	_, newStones := sc.Rules.Apply(stone)
+	for _, newStone := range newStones {
+		count += sc.Count(newStone, blinksRemaining-1)
+	}
+
+	sc.mutex.Lock()
+	if sc.maxStoneLength == 0 || len(stone) <= sc.maxStoneLength {
+		sc.memo[key] = count
+	}
+	sc.mutex.Unlock()
+
+	return count
+}
+
+// CountOnce counts like Count but without memoising the stone itself, for
+// stones that are only ever counted once, such as each seed in a search.
+func (sc *StoneCounter) CountOnce(stone string, blinksRemaining int) uint64 {
+	if blinksRemaining <= 0 {
+		return 1
+	}
+
+	sc.mutex.RLock()
+	count, ok := sc.memo[memoKey{stone: stone, blinks: blinksRemaining}]
+	sc.mutex.RUnlock()
+	if ok {
+		return count
+	}
+
+	_, newStones := sc.Rules.Apply(stone)
+	for _, newStone := range newStones {
+		count += sc.Count(newStone, blinksRemaining-1)
+	}
+
+	return count
+}
+
+// LimitMemo stops stones longer than the given length being memoised, or lifts
+// the limit when it is 0, and returns the previous limit. There are only so
+// many short stones, so the limit bounds how large the table can grow.
+func (sc *StoneCounter) LimitMemo(maxStoneLength int) int {
+	sc.mutex.Lock()
+	defer sc.mutex.Unlock()
+
+	previous := sc.maxStoneLength
+	sc.maxStoneLength = maxStoneLength
+	return previous
+}
+
+func (sc *StoneCounter) CountAll(stoneCounts map[string]uint64, blinks int) uint64 {
+	var totalStoneCount uint64 = 0
+	for stone, count := range stoneCounts {
+		totalStoneCount += count * sc.Count(stone, blinks)
+	}
+
+	return totalStoneCount
+}
+
+func (sc *StoneCounter) Len() int {
+	sc.mutex.RLock()
+	defer sc.mutex.RUnlock()
+
+	return len(sc.memo)
+}
+
+func (sc *StoneCounter) Save(path string) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	err = file.Chmod(0644)
+	if err == nil {
+		_, err = sc.WriteTo(file)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), path)
+}
+
+// WriteTo encodes the memo table as the magic string, a version byte, the base
+// and an entry count, followed by one (stone length, stone, blinks, count) record per
+// entry. Every integer is a uvarint and the records are sorted, so the same
+// table always produces the same bytes.
+func (sc *StoneCounter) WriteTo(w io.Writer) (int64, error) {
+	sc.mutex.RLock()
+	keys := make([]memoKey, 0, len(sc.memo))
+	for key := range sc.memo {
+		keys = append(keys, key)
+	}
+	counts := make([]uint64, len(keys))
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].blinks != keys[j].blinks {
+			return keys[i].blinks < keys[j].blinks
+		}
+		return IsLargerStone(keys[j].stone, keys[i].stone)
+	})
+	for i, key := range keys {
+		counts[i] = sc.memo[key]
+	}
+	sc.mutex.RUnlock()
+
+	writer := bufio.NewWriter(w)
+	var written int64 = 0
+	write := func(buf []byte) error {
+		n, err := writer.Write(buf)
+		written += int64(n)
+		return err
+	}
+
+	header := append([]byte(memoMagic), memoVersion, byte(sc.Rules.Base))
+	header = binary.AppendUvarint(header, uint64(len(keys)))
+	if err := write(header); err != nil {
+		return written, err
+	}
+
+	record := make([]byte, 0, 64)
+	for i, key := range keys {
+		record = binary.AppendUvarint(record[:0], uint64(len(key.stone)))
+		record = append(record, key.stone...)
+		record = binary.AppendUvarint(record, uint64(key.blinks))
+		record = binary.AppendUvarint(record, counts[i])
+		if err := write(record); err != nil {
+			return written, err
+		}
+	}
+
+	return written, writer.Flush()
+}
+
+// ReadFrom merges a table written by WriteTo into the memo table. Version 1
+// tables predate the base byte and always hold decimal stones.
+func (sc *StoneCounter) ReadFrom(r io.Reader) (int64, error) {
+	reader := &countingReader{reader: bufio.NewReader(r)}
+
+	header := make([]byte, len(memoMagic)+1)
+	if _, err := io.ReadFull(reader, header); err != nil {
+		return reader.read, err
+	}
+	if string(header[:len(memoMagic)]) != memoMagic {
+		return reader.read, fmt.Errorf("not a stone memo table")
+	}
+
+	base := 10
+	switch version := header[len(memoMagic)]; version {
+	case 1:
+	case memoVersion:
+		baseByte, err := reader.ReadByte()
+		if err != nil {
+			return reader.read, err
+		}
+		base = int(baseByte)
+	default:
+		return reader.read, fmt.Errorf("unsupported memo table version %d", version)
+	}
+
+	if base != sc.Rules.Base {
+		return reader.read, fmt.Errorf("memo table is for base %d, not base %d", base, sc.Rules.Base)
+	}
+
+	numEntries, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return reader.read, err
+	}
+
+	memo := make(map[memoKey]uint64)
+	for entry := uint64(0); entry < numEntries; entry++ {
+		stoneLength, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return reader.read, err
+		}
+
+		if stoneLength == 0 || stoneLength > maxMemoStoneLength {
+			return reader.read, fmt.Errorf("entry %d has a stone %d digits long, expected 1 to %d", entry, stoneLength, maxMemoStoneLength)
+		}
+
+		stone := make([]byte, stoneLength)
+		if _, err := io.ReadFull(reader, stone); err != nil {
+			return reader.read, err
+		}
+
+		parsed, err := sc.Rules.ParseStone(string(stone))
+		if err != nil || parsed != string(stone) {
+			return reader.read, fmt.Errorf("entry %d has an invalid stone %q", entry, stone)
+		}
+
+		blinks, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return reader.read, err
+		}
+
+		if blinks > math.MaxInt32 {
+			return reader.read, fmt.Errorf("entry %d is for %d blinks, which is too many", entry, blinks)
+		}
+
+		count, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return reader.read, err
+		}
+
+		memo[memoKey{stone: string(stone), blinks: int(blinks)}] = count
+	}
+
+	sc.mutex.Lock()
+	for key, count := range memo {
+		sc.memo[key] = count
+	}
+	sc.mutex.Unlock()
+
+	return reader.read, nil
+}
+
+type countingReader struct {
+	reader *bufio.Reader
+	read   int64
+}
+
+func (cr *countingReader) Read(p []byte) (int, error) {
+	n, err := cr.reader.Read(p)
+	cr.read += int64(n)
+	return n, err
+}
+
+func (cr *countingReader) ReadByte() (byte, error) {
+	b, err := cr.reader.ReadByte()
+	if err == nil {
+		cr.read++
+	}
+	return b, err
+}
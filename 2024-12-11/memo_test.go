@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMemoTableRoundTrip(t *testing.T) {
+	for _, base := range []int{10, 16} {
+		rules, _ := NewStoneRules(base)
+		stoneCounter := NewStoneCounter(rules)
+		expected := stoneCounter.CountAll(map[string]uint64{"125": 1, "17": 1, "0": 2}, 30)
+
+		var table bytes.Buffer
+		written, err := stoneCounter.WriteTo(&table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if written != int64(table.Len()) {
+			t.Errorf("base %d: WriteTo reported %d bytes but wrote %d", base, written, table.Len())
+		}
+
+		loaded := NewStoneCounter(rules)
+		read, err := loaded.ReadFrom(bytes.NewReader(table.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if read != written || loaded.Len() != stoneCounter.Len() {
+			t.Errorf("base %d: read %d bytes and %d entries, expected %d and %d", base, read, loaded.Len(), written, stoneCounter.Len())
+		}
+
+		// The same table always encodes to the same bytes.
+		var rewritten bytes.Buffer
+		_, err = loaded.WriteTo(&rewritten)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(table.Bytes(), rewritten.Bytes()) {
+			t.Errorf("base %d: rewriting the loaded table changed its bytes", base)
+		}
+
+		if count := loaded.CountAll(map[string]uint64{"125": 1, "17": 1, "0": 2}, 30); count != expected {
+			t.Errorf("base %d: loaded table counted %d stones, expected %d", base, count, expected)
+		}
+	}
+}
+
+func TestSaveAndLoadStoneCounter(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+	path := filepath.Join(t.TempDir(), "stones.memo")
+
+	loaded, err := LoadStoneCounter(path, rules)
+	if err != nil || loaded.Len() != 0 {
+		t.Fatalf("expected a missing table to load empty, got %d entries and %v", loaded.Len(), err)
+	}
+
+	stoneCounter := NewStoneCounter(rules)
+	stoneCounter.Count("125", 25)
+	err = stoneCounter.Save(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err = LoadStoneCounter(path, rules)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Len() != stoneCounter.Len() {
+		t.Errorf("expected %d entries, loaded %d", stoneCounter.Len(), loaded.Len())
+	}
+
+	hexRules, _ := NewStoneRules(16)
+	_, err = LoadStoneCounter(path, hexRules)
+	if err == nil || !strings.Contains(err.Error(), "reading memo table") {
+		t.Errorf("expected a decimal table to be rejected in base 16, got %v", err)
+	}
+}
+
+// versionOneTable encodes the entries the way version 1 did, without a base.
+func versionOneTable(entries map[memoKey]uint64) []byte {
+	table := append([]byte(memoMagic), 1)
+	table = binary.AppendUvarint(table, uint64(len(entries)))
+	for key, count := range entries {
+		table = binary.AppendUvarint(table, uint64(len(key.stone)))
+		table = append(table, key.stone...)
+		table = binary.AppendUvarint(table, uint64(key.blinks))
+		table = binary.AppendUvarint(table, count)
+	}
+
+	return table
+}
+
+func TestReadVersionOneTable(t *testing.T) {
+	entries := map[memoKey]uint64{
+		{stone: "0", blinks: 1}:    1,
+		{stone: "125", blinks: 6}:  7,
+		{stone: "2024", blinks: 3}: 4,
+	}
+
+	rules, _ := NewStoneRules(10)
+	stoneCounter := NewStoneCounter(rules)
+	_, err := stoneCounter.ReadFrom(bytes.NewReader(versionOneTable(entries)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for key, count := range entries {
+		if stoneCounter.memo[key] != count {
+			t.Errorf("expected %s after %d blinks to be memoised as %d, got %d", key.stone, key.blinks, count, stoneCounter.memo[key])
+		}
+	}
+	if stoneCounter.Count("125", 6) != 7 {
+		t.Errorf("expected the loaded count to be used")
+	}
+
+	hexRules, _ := NewStoneRules(16)
+	_, err = NewStoneCounter(hexRules).ReadFrom(bytes.NewReader(versionOneTable(entries)))
+	if err == nil {
+		t.Errorf("expected a version 1 table to be rejected in base 16")
+	}
+}
+
+func TestReadCorruptTables(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+
+	var valid bytes.Buffer
+	stoneCounter := NewStoneCounter(rules)
+	stoneCounter.Count("125", 10)
+	_, err := stoneCounter.WriteTo(&valid)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := append([]byte(memoMagic), memoVersion, 10, 1)
+	tables := map[string][]byte{
+		"empty":            {},
+		"wrong magic":      []byte("STONEMEMX\x02\x0a\x00"),
+		"unknown version":  append([]byte(memoMagic), 9, 10, 0),
+		"huge stone":       binary.AppendUvarint(bytes.Clone(header), 1<<62),
+		"empty stone":      append(bytes.Clone(header), 0, 1, 1),
+		"invalid stone":    append(bytes.Clone(header), 2, 'x', '7', 1, 1),
+		"leading zero":     append(bytes.Clone(header), 2, '0', '7', 1, 1),
+		"too many blinks":  binary.AppendUvarint(append(bytes.Clone(header), 1, '7'), 1<<40),
+		"truncated":        valid.Bytes()[:valid.Len()-3],
+		"truncated header": valid.Bytes()[:len(memoMagic)+1],
+	}
+
+	for name, table := range tables {
+		_, err := NewStoneCounter(rules).ReadFrom(bytes.NewReader(table))
+		if err == nil {
+			t.Errorf("expected the %s table to be rejected", name)
+		}
+	}
+}
+
+func TestConcurrentCount(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+	stones := []string{"0", "1", "125", "17", "2024", "99", "1000", "28676032"}
+
+	expected := make([]uint64, len(stones))
+	for i, stone := range stones {
+		expected[i] = NewStoneCounter(rules).Count(stone, 40)
+	}
+
+	// Every goroutine counts every stone, so they race to fill in the same
+	// entries of the shared table.
+	stoneCounter := NewStoneCounter(rules)
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < 8; worker++ {
+		waitGroup.Add(1)
+		go func(worker int) {
+			defer waitGroup.Done()
+			for i := range stones {
+				stone := stones[(i+worker)%len(stones)]
+				if count := stoneCounter.Count(stone, 40); count != expected[(i+worker)%len(stones)] {
+					t.Errorf("worker %d counted %d stones from %s, expected %d", worker, count, stone, expected[(i+worker)%len(stones)])
+				}
+			}
+		}(worker)
+	}
+	waitGroup.Wait()
+}
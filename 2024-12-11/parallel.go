@@ -0,0 +1,115 @@
+package main
+
+import (
+	"maps"
+	"sync"
+)
+
+// BlinkParallel partitions the stones by hash across the given number of
+// workers, blinks the partitions with BlinkPartitions and joins them back
+// together. The counts are summed exactly, so the result is the same as
+// Blink's.
+func BlinkParallel(stoneCounts map[string]uint64, rules StoneRules, workers int) map[string]uint64 {
+	if workers <= 1 || len(stoneCounts) < workers {
+		return Blink(stoneCounts, rules)
+	}
+
+	return JoinPartitions(BlinkPartitions(PartitionStones(stoneCounts, workers), rules))
+}
+
+// stoneHash is FNV-1a over the stone's digits, written out so that hashing a
+// stone does not allocate.
+func stoneHash(stone string) uint32 {
+	hash := uint32(2166136261)
+	for i := 0; i < len(stone); i++ {
+		hash ^= uint32(stone[i])
+		hash *= 16777619
+	}
+
+	return hash
+}
+
+// PartitionStones splits the stones into the given number of partitions,
+// putting each stone in the partition its hash picks.
+func PartitionStones(stoneCounts map[string]uint64, numPartitions int) []map[string]uint64 {
+	partitions := make([]map[string]uint64, numPartitions)
+	for i := range partitions {
+		partitions[i] = make(map[string]uint64, len(stoneCounts)/numPartitions+1)
+	}
+	for stone, count := range stoneCounts {
+		partitions[stoneHash(stone)%uint32(numPartitions)][stone] = count
+	}
+
+	return partitions
+}
+
+// JoinPartitions copies the partitions into one map. No two partitions hold
+// the same stone, so nothing needs adding up.
+func JoinPartitions(partitions []map[string]uint64) map[string]uint64 {
+	size := 0
+	for _, partition := range partitions {
+		size += len(partition)
+	}
+
+	stoneCounts := make(map[string]uint64, size)
+	for _, partition := range partitions {
+		maps.Copy(stoneCounts, partition)
+	}
+
+	return stoneCounts
+}
+
+// BlinkPartitions blinks each partition on its own goroutine, sending every
+// new stone to the partition its hash picks. Each goroutine then adds up the
+// counts sent to its own partition. The partitions never share a stone, so
+// both halves run in parallel and the partitions are ready for the next blink
+// without being joined.
+func BlinkPartitions(partitions []map[string]uint64, rules StoneRules) []map[string]uint64 {
+	numPartitions := len(partitions)
+	if numPartitions == 1 {
+		return []map[string]uint64{Blink(partitions[0], rules)}
+	}
+
+	// sent[from][to] holds the stones the partition from sends to the
+	// partition to.
+	sent := make([][]map[string]uint64, numPartitions)
+	forEachPartition(numPartitions, func(from int) {
+		outgoing := make([]map[string]uint64, numPartitions)
+		for to := range outgoing {
+			outgoing[to] = make(map[string]uint64, 2*len(partitions[from])/numPartitions+1)
+		}
+
+		for stone, count := range partitions[from] {
+			_, newStones := rules.Apply(stone)
+			for _, newStone := range newStones {
+				AddOrIncrementStoneCount(outgoing[stoneHash(newStone)%uint32(numPartitions)], newStone, count)
+			}
+		}
+		sent[from] = outgoing
+	})
+
+	blinked := make([]map[string]uint64, numPartitions)
+	forEachPartition(numPartitions, func(to int) {
+		partition := sent[0][to]
+		for from := 1; from < numPartitions; from++ {
+			for stone, count := range sent[from][to] {
+				AddOrIncrementStoneCount(partition, stone, count)
+			}
+		}
+		blinked[to] = partition
+	})
+
+	return blinked
+}
+
+func forEachPartition(numPartitions int, work func(partition int)) {
+	var waitGroup sync.WaitGroup
+	for partition := 0; partition < numPartitions; partition++ {
+		waitGroup.Add(1)
+		go func(partition int) {
+			defer waitGroup.Done()
+			work(partition)
+		}(partition)
+	}
+	waitGroup.Wait()
+}
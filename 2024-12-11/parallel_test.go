@@ -0,0 +1,165 @@
+package main
+
+import (
+	"hash/fnv"
+	"maps"
+	"strconv"
+	"testing"
+)
+
+func blinkTimes(stoneCounts map[string]uint64, blinks int, blink func(map[string]uint64) map[string]uint64) map[string]uint64 {
+	for i := 0; i < blinks; i++ {
+		stoneCounts = blink(stoneCounts)
+	}
+
+	return stoneCounts
+}
+
+// wideStoneCounts seeds a map with many distinct stones, so every shard of a
+// parallel blink has plenty of work.
+func wideStoneCounts(size int) map[string]uint64 {
+	stoneCounts := make(map[string]uint64, size)
+	for i := 0; i < size; i++ {
+		stoneCounts[strconv.Itoa(i*7919)] = uint64(i%5 + 1)
+	}
+
+	return stoneCounts
+}
+
+func TestBlinkParallelMatchesBlink(t *testing.T) {
+	for _, base := range []int{10, 16} {
+		rules, err := NewStoneRules(base)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, seeds := range []map[string]uint64{
+			{"125": 1, "17": 1},
+			{"0": 3, "1": 1, "10": 2, "99": 1, "999": 1},
+			wideStoneCounts(500),
+		} {
+			expected := blinkTimes(seeds, 25, func(stoneCounts map[string]uint64) map[string]uint64 {
+				return Blink(stoneCounts, rules)
+			})
+
+			for _, workers := range []int{0, 1, 2, 3, 4, 8, 17} {
+				actual := blinkTimes(seeds, 25, func(stoneCounts map[string]uint64) map[string]uint64 {
+					return BlinkParallel(stoneCounts, rules, workers)
+				})
+
+				if !maps.Equal(expected, actual) {
+					t.Errorf("base %d, %d workers: BlinkParallel gave %d distinct stones and %d in total, Blink gave %d and %d",
+						base, workers, len(actual), GetTotalStoneCount(actual), len(expected), GetTotalStoneCount(expected))
+				}
+			}
+		}
+	}
+}
+
+func TestBlinkParallelExample(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+	stoneCounts := blinkTimes(map[string]uint64{"125": 1, "17": 1}, 25, func(stoneCounts map[string]uint64) map[string]uint64 {
+		return BlinkParallel(stoneCounts, rules, 4)
+	})
+
+	if total := GetTotalStoneCount(stoneCounts); total != 55312 {
+		t.Errorf("expected 55312 stones after 25 blinks, got %d", total)
+	}
+}
+
+func TestStoneHashIsFNV1a(t *testing.T) {
+	for _, stone := range []string{"", "0", "125", "2024", "ff0a", "253000000000000000000"} {
+		hash := fnv.New32a()
+		hash.Write([]byte(stone))
+		if stoneHash(stone) != hash.Sum32() {
+			t.Errorf("stone %q: expected hash %d, got %d", stone, hash.Sum32(), stoneHash(stone))
+		}
+	}
+}
+
+func TestBlinkPartitionsKeepsStonesInTheirPartition(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+	expected := blinkTimes(wideStoneCounts(500), 10, func(stoneCounts map[string]uint64) map[string]uint64 {
+		return Blink(stoneCounts, rules)
+	})
+
+	for _, numPartitions := range []int{1, 2, 5} {
+		partitions := PartitionStones(wideStoneCounts(500), numPartitions)
+		for blink := 0; blink < 10; blink++ {
+			partitions = BlinkPartitions(partitions, rules)
+		}
+
+		for i, partition := range partitions {
+			for stone := range partition {
+				if int(stoneHash(stone)%uint32(numPartitions)) != i {
+					t.Fatalf("%d partitions: stone %s is in partition %d", numPartitions, stone, i)
+				}
+			}
+		}
+		if actual := JoinPartitions(partitions); !maps.Equal(expected, actual) {
+			t.Errorf("%d partitions: got %d distinct stones and %d in total, expected %d and %d",
+				numPartitions, len(actual), GetTotalStoneCount(actual), len(expected), GetTotalStoneCount(expected))
+		}
+	}
+}
+
+// benchmarkStoneCounts are the few thousand distinct stones any seeds settle
+// into after enough blinks, and wider maps of ever more distinct stones.
+func benchmarkStoneCounts(rules StoneRules) []struct {
+	name        string
+	stoneCounts map[string]uint64
+} {
+	settled := blinkTimes(wideStoneCounts(2000), 20, func(stoneCounts map[string]uint64) map[string]uint64 {
+		return Blink(stoneCounts, rules)
+	})
+
+	return []struct {
+		name        string
+		stoneCounts map[string]uint64
+	}{
+		{"settled", settled},
+		{"10^4-stones", wideStoneCounts(10000)},
+		{"10^5-stones", wideStoneCounts(100000)},
+		{"10^6-stones", wideStoneCounts(1000000)},
+	}
+}
+
+func BenchmarkBlink(b *testing.B) {
+	rules, _ := NewStoneRules(10)
+	for _, size := range benchmarkStoneCounts(rules) {
+		b.Run(size.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				Blink(size.stoneCounts, rules)
+			}
+		})
+	}
+}
+
+func BenchmarkBlinkParallel(b *testing.B) {
+	rules, _ := NewStoneRules(10)
+	for _, size := range benchmarkStoneCounts(rules) {
+		for _, workers := range []int{2, 4, 8} {
+			b.Run(size.name+"/"+strconv.Itoa(workers)+"-workers", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					BlinkParallel(size.stoneCounts, rules, workers)
+				}
+			})
+		}
+	}
+}
+
+// BenchmarkBlinkPartitions blinks stones that are already partitioned, as they
+// are for every blink after the first.
+func BenchmarkBlinkPartitions(b *testing.B) {
+	rules, _ := NewStoneRules(10)
+	for _, size := range benchmarkStoneCounts(rules) {
+		for _, workers := range []int{2, 4, 8} {
+			partitions := PartitionStones(size.stoneCounts, workers)
+			b.Run(size.name+"/"+strconv.Itoa(workers)+"-workers", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					BlinkPartitions(partitions, rules)
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+type StoneCount struct {
+	Stone string `json:"stone"`
+	Count uint64 `json:"count"`
+}
+
+type BlinkReport struct {
+	Blink          int          `json:"blink"`
+	TotalStones    uint64       `json:"totalStones"`
+	DistinctStones int          `json:"distinctStones"`
+	LargestStone   string       `json:"largestStone"`
+	MostCommon     []StoneCount `json:"mostCommon"`
+}
+
+func NewBlinkReport(blink int, stoneCounts map[string]uint64, topK int) BlinkReport {
+	report := BlinkReport{
+		Blink:          blink,
+		TotalStones:    GetTotalStoneCount(stoneCounts),
+		DistinctStones: len(stoneCounts),
+		MostCommon:     GetMostCommonStones(stoneCounts, topK),
+	}
+
+	for stone := range stoneCounts {
+		if report.LargestStone == "" || IsLargerStone(stone, report.LargestStone) {
+			report.LargestStone = stone
+		}
+	}
+
+	return report
+}
+
+// Stones have no leading zeros, so a longer stone is always the larger one and
+// stones of equal length compare lexically.
+func IsLargerStone(stone string, other string) bool {
+	if len(stone) != len(other) {
+		return len(stone) > len(other)
+	}
+
+	return stone > other
+}
+
+func GetMostCommonStones(stoneCounts map[string]uint64, topK int) []StoneCount {
+	mostCommon := make([]StoneCount, 0, len(stoneCounts))
+	for stone, count := range stoneCounts {
+		mostCommon = append(mostCommon, StoneCount{Stone: stone, Count: count})
+	}
+
+	sort.Slice(mostCommon, func(i, j int) bool {
+		if mostCommon[i].Count != mostCommon[j].Count {
+			return mostCommon[i].Count > mostCommon[j].Count
+		}
+		return IsLargerStone(mostCommon[j].Stone, mostCommon[i].Stone)
+	})
+
+	if topK < 0 {
+		topK = 0
+	}
+	if topK < len(mostCommon) {
+		mostCommon = mostCommon[:topK]
+	}
+
+	return mostCommon
+}
+
+func WriteReport(w io.Writer, format string, reports []BlinkReport, topK int) error {
+	switch format {
+	case "csv":
+		return WriteCsvReport(w, reports, topK)
+	case "json":
+		return WriteJsonReport(w, reports)
+	default:
+		return fmt.Errorf("unknown report format %q, expected csv or json", format)
+	}
+}
+
+func WriteCsvReport(w io.Writer, reports []BlinkReport, topK int) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{"blink", "total_stones", "distinct_stones", "largest_stone"}
+	for rank := 1; rank <= topK; rank++ {
+		header = append(header, fmt.Sprintf("top_%d_stone", rank), fmt.Sprintf("top_%d_count", rank))
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, report := range reports {
+		record := []string{
+			strconv.Itoa(report.Blink),
+			strconv.FormatUint(report.TotalStones, 10),
+			strconv.Itoa(report.DistinctStones),
+			report.LargestStone,
+		}
+		for rank := 0; rank < topK; rank++ {
+			if rank < len(report.MostCommon) {
+				record = append(record, report.MostCommon[rank].Stone, strconv.FormatUint(report.MostCommon[rank].Count, 10))
+			} else {
+				record = append(record, "", "")
+			}
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteJsonReport(w io.Writer, reports []BlinkReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
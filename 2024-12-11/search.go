@@ -0,0 +1,167 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParseSeedRange reads a range of seeds written as from-to, with both bounds in
+// the stones' base.
+func ParseSeedRange(seedRange string, rules StoneRules) (uint64, uint64, error) {
+	bounds := strings.SplitN(seedRange, "-", 2)
+	if len(bounds) != 2 {
+		return 0, 0, fmt.Errorf("invalid seed range %q, expected from-to", seedRange)
+	}
+
+	from, err := strconv.ParseUint(strings.TrimSpace(bounds[0]), rules.Base, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid seed range %q in base %d: %w", seedRange, rules.Base, err)
+	}
+
+	to, err := strconv.ParseUint(strings.TrimSpace(bounds[1]), rules.Base, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid seed range %q in base %d: %w", seedRange, rules.Base, err)
+	}
+
+	if to < from {
+		return 0, 0, fmt.Errorf("invalid seed range %q: %s is less than %s", seedRange, rules.Format(to), rules.Format(from))
+	}
+
+	return from, to, nil
+}
+
+// searchMemoStones is roughly how many distinct stones a search may memoise.
+// Almost every seed leads to a few long stones no other seed reaches, so when
+// every stone was memoised the table grew by around 180 bytes a seed, and a
+// search of two million seeds peaked at 360 MB. Only stones short enough that
+// there are at most this many of them are memoised, and the long ones soon
+// split into short ones anyway.
+const searchMemoStones = 1 << 14
+
+// maxSeedRange caps how many seeds one search may count. With only short
+// stones memoised a search stayed around 30 MB however many seeds it counted,
+// but every seed still takes time, up to around 8 microseconds on four workers,
+// so the largest search takes about half an hour.
+const maxSeedRange = 1 << 28
+
+// RankSeeds counts the stones every single-stone seed in [from, to] produces
+// after the given number of blinks and returns the top seeds ordered by count,
+// breaking ties by the smaller seed. Seeds are counted through the shared memo
+// table, so once one seed has been explored any other seed that reaches the
+// same stones reuses its counts. Neither the seeds themselves nor any other
+// long stones are memoised, and each worker only keeps its own top seeds in a
+// bounded heap, so memory does not grow with the size of the range.
+func RankSeeds(stoneCounter *StoneCounter, from uint64, to uint64, blinks int, fewest bool, workers int, top int) ([]StoneCount, error) {
+	if to < from {
+		return nil, fmt.Errorf("invalid seed range: %s is less than %s", stoneCounter.Rules.Format(to), stoneCounter.Rules.Format(from))
+	}
+	if to-from >= maxSeedRange {
+		return nil, fmt.Errorf("seed range %s-%s is too large, it can cover at most %d seeds",
+			stoneCounter.Rules.Format(from), stoneCounter.Rules.Format(to), uint64(maxSeedRange))
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	if top < 1 {
+		return []StoneCount{}, nil
+	}
+
+	maxStoneLength := 1
+	for stones := stoneCounter.Rules.Base; stones*stoneCounter.Rules.Base <= searchMemoStones; stones *= stoneCounter.Rules.Base {
+		maxStoneLength++
+	}
+	previousLimit := stoneCounter.LimitMemo(maxStoneLength)
+	defer stoneCounter.LimitMemo(previousLimit)
+
+	ranksBefore := func(a rankedSeed, b rankedSeed) bool {
+		if a.Count != b.Count {
+			return (a.Count < b.Count) == fewest
+		}
+		return a.seed < b.seed
+	}
+
+	heaps := make([]*seedHeap, workers)
+	var waitGroup sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		heaps[worker] = &seedHeap{ranksBefore: ranksBefore}
+
+		waitGroup.Add(1)
+		go func(worker int) {
+			defer waitGroup.Done()
+			for offset := uint64(worker); offset <= to-from; offset += uint64(workers) {
+				seed := from + offset
+				stone := stoneCounter.Rules.Format(seed)
+				heaps[worker].Offer(rankedSeed{
+					seed:       seed,
+					StoneCount: StoneCount{Stone: stone, Count: stoneCounter.CountOnce(stone, blinks)},
+				}, top)
+			}
+		}(worker)
+	}
+	waitGroup.Wait()
+
+	merged := &seedHeap{ranksBefore: ranksBefore}
+	for _, workerHeap := range heaps {
+		for _, ranked := range workerHeap.seeds {
+			merged.Offer(ranked, top)
+		}
+	}
+
+	sort.Slice(merged.seeds, func(i, j int) bool {
+		return ranksBefore(merged.seeds[i], merged.seeds[j])
+	})
+
+	rankings := make([]StoneCount, 0, len(merged.seeds))
+	for _, ranked := range merged.seeds {
+		rankings = append(rankings, ranked.StoneCount)
+	}
+
+	return rankings, nil
+}
+
+type rankedSeed struct {
+	seed uint64
+	StoneCount
+}
+
+// seedHeap keeps the best seeds offered to it with the worst of them on top,
+// ready to be replaced by a better one.
+type seedHeap struct {
+	seeds       []rankedSeed
+	ranksBefore func(a rankedSeed, b rankedSeed) bool
+}
+
+func (sh *seedHeap) Len() int {
+	return len(sh.seeds)
+}
+
+func (sh *seedHeap) Less(i int, j int) bool {
+	return sh.ranksBefore(sh.seeds[j], sh.seeds[i])
+}
+
+func (sh *seedHeap) Swap(i int, j int) {
+	sh.seeds[i], sh.seeds[j] = sh.seeds[j], sh.seeds[i]
+}
+
+func (sh *seedHeap) Push(x any) {
+	sh.seeds = append(sh.seeds, x.(rankedSeed))
+}
+
+func (sh *seedHeap) Pop() any {
+	last := sh.seeds[len(sh.seeds)-1]
+	sh.seeds = sh.seeds[:len(sh.seeds)-1]
+	return last
+}
+
+func (sh *seedHeap) Offer(ranked rankedSeed, size int) {
+	if sh.Len() < size {
+		heap.Push(sh, ranked)
+	} else if sh.ranksBefore(ranked, sh.seeds[0]) {
+		sh.seeds[0] = ranked
+		heap.Fix(sh, 0)
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+)
+
+func TestParseSeedRange(t *testing.T) {
+	tests := []struct {
+		base      int
+		seedRange string
+		from      uint64
+		to        uint64
+	}{
+		{10, "3-40", 3, 40},
+		{10, " 7 - 7 ", 7, 7},
+		{16, "10-1f", 16, 31},
+		{16, "A-FF", 10, 255},
+		{2, "101-1111", 5, 15},
+		{36, "z-10", 35, 36},
+	}
+
+	for _, test := range tests {
+		rules, _ := NewStoneRules(test.base)
+		from, to, err := ParseSeedRange(test.seedRange, rules)
+		if err != nil {
+			t.Errorf("base %d, range %q: %v", test.base, test.seedRange, err)
+		} else if from != test.from || to != test.to {
+			t.Errorf("base %d, range %q: expected %d-%d, got %d-%d", test.base, test.seedRange, test.from, test.to, from, to)
+		}
+	}
+
+	for _, invalid := range []struct {
+		base      int
+		seedRange string
+	}{{10, "10-1f"}, {10, "5"}, {10, "9-3"}, {16, "1f-10"}, {8, "0-8"}, {2, "-1"}} {
+		rules, _ := NewStoneRules(invalid.base)
+		_, _, err := ParseSeedRange(invalid.seedRange, rules)
+		if err == nil {
+			t.Errorf("base %d: expected range %q to be rejected", invalid.base, invalid.seedRange)
+		}
+	}
+}
+
+func TestRankSeedsMatchesSortedCounts(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+
+	expected := make([]StoneCount, 0)
+	for seed := uint64(3); seed <= 40; seed++ {
+		stoneCounts := map[string]uint64{strconv.FormatUint(seed, 10): 1}
+		for blink := 0; blink < 15; blink++ {
+			stoneCounts = Blink(stoneCounts, rules)
+		}
+		expected = append(expected, StoneCount{Stone: strconv.FormatUint(seed, 10), Count: GetTotalStoneCount(stoneCounts)})
+	}
+
+	for _, fewest := range []bool{false, true} {
+		sorted := slices.Clone(expected)
+		sort.SliceStable(sorted, func(i, j int) bool {
+			if fewest {
+				return sorted[i].Count < sorted[j].Count
+			}
+			return sorted[i].Count > sorted[j].Count
+		})
+
+		for _, workers := range []int{1, 3, 8} {
+			for _, top := range []int{1, 5, 38, 100} {
+				rankings, err := RankSeeds(NewStoneCounter(rules), 3, 40, 15, fewest, workers, top)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				want := sorted[:min(top, len(sorted))]
+				if !slices.Equal(rankings, want) {
+					t.Errorf("fewest %v, %d workers, top %d: got %v, want %v", fewest, workers, top, rankings, want)
+				}
+			}
+		}
+	}
+}
+
+func TestRankSeedsRejectsHugeRanges(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+
+	for _, bounds := range [][2]uint64{{0, math.MaxUint64}, {1, 1 << 40}, {math.MaxUint64 - maxSeedRange, math.MaxUint64}} {
+		_, err := RankSeeds(NewStoneCounter(rules), bounds[0], bounds[1], 5, false, 2, 5)
+		if err == nil {
+			t.Errorf("expected range %d-%d to be rejected", bounds[0], bounds[1])
+		}
+	}
+}
+
+func TestRankSeedsAtTheTopOfTheRange(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+
+	rankings, err := RankSeeds(NewStoneCounter(rules), math.MaxUint64-4, math.MaxUint64, 3, false, 3, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rankings) != 5 {
+		t.Errorf("expected all 5 seeds to be ranked, got %v", rankings)
+	}
+}
+
+func TestRankSeedsOnlyMemoisesShortStones(t *testing.T) {
+	rules, _ := NewStoneRules(10)
+	stoneCounter := NewStoneCounter(rules)
+
+	_, err := RankSeeds(stoneCounter, 100000, 120000, 25, false, 4, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stoneCounter.mutex.RLock()
+	for key := range stoneCounter.memo {
+		if len(key.stone) > 4 {
+			t.Fatalf("expected only stones of up to 4 digits to be memoised, found %s", key.stone)
+		}
+	}
+	stoneCounter.mutex.RUnlock()
+
+	// The limit only lasts for the search.
+	stoneCounter.Count("123456", 5)
+	if _, ok := stoneCounter.memo[memoKey{stone: "123456", blinks: 5}]; !ok {
+		t.Errorf("expected counting after the search to memoise long stones again")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var eightWayDirections = []Point{
+	{Row: -1, Col: -1}, {Row: -1, Col: 0}, {Row: -1, Col: 1}, {Row: 0, Col: 1},
+	{Row: 1, Col: 1}, {Row: 1, Col: 0}, {Row: 1, Col: -1}, {Row: 0, Col: -1},
+}
+
+// ParseConnectivity accepts 4, 8, or a custom stencil of row,column offsets
+// separated by semicolons.
+func ParseConnectivity(spec string) ([]Point, error) {
+	switch strings.TrimSpace(spec) {
+	case "4":
+		return orthogonalDirections, nil
+	case "8":
+		return eightWayDirections, nil
+	}
+
+	stencil := make([]Point, 0)
+	for _, offset := range strings.Split(spec, ";") {
+		coordinates := strings.Split(offset, ",")
+		if len(coordinates) != 2 {
+			return nil, fmt.Errorf("invalid neighbour offset %q in stencil %q, expected row,column", offset, spec)
+		}
+
+		row, err := strconv.Atoi(strings.TrimSpace(coordinates[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid neighbour offset %q in stencil %q: %w", offset, spec, err)
+		}
+
+		column, err := strconv.Atoi(strings.TrimSpace(coordinates[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid neighbour offset %q in stencil %q: %w", offset, spec, err)
+		}
+
+		if row == 0 && column == 0 {
+			return nil, fmt.Errorf("invalid neighbour offset %q in stencil %q: a cell cannot neighbour itself", offset, spec)
+		}
+
+		stencil = append(stencil, Point{Row: row, Col: column})
+	}
+
+	return stencil, nil
+}
+
+// SetConnectivity changes which cells join a region in the next examination.
+// Adjacency has to be symmetric for regions to be well defined, so the mirror
+// of every offset in the stencil is added if it is missing.
+func (fc *FencingCalculator) SetConnectivity(stencil []Point) {
+	connectivity := make([]Point, 0, len(stencil)*2)
+	seen := make(map[Point]bool)
+	for _, offset := range stencil {
+		for _, direction := range []Point{offset, {Row: -offset.Row, Col: -offset.Col}} {
+			if !seen[direction] {
+				seen[direction] = true
+				connectivity = append(connectivity, direction)
+			}
+		}
+	}
+
+	fc.connectivity = connectivity
+}
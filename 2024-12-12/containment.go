@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ExamineContainment works out which regions are fully enclosed by another
+// region and links every plot to the innermost region enclosing it. A region
+// encloses another when every path from the other region out of the garden has
+// to cross it, so regions touching the border are never enclosed.
+// Paths out step orthogonally from one region to the next, the complement of
+// eight-way connectivity, so a ring joined only at its corners still seals in
+// what is inside it. Within a region a path may follow the region's own
+// connectivity, so where two regions cross at a corner each is a gap in the
+// other.
+//
+// In the graph of which regions touch, with the outside of the garden as one
+// more region touching every border region, the regions enclosing a region are
+// exactly those every path from the outside to it passes through. Those are
+// found for every region at once with one depth first search from the outside,
+// as in finding articulation points, so the cost is linear in the garden's
+// size. It runs the first time the containment forest is asked for, as plain
+// pricing never needs it.
+func (fc *FencingCalculator) ExamineContainment() {
+	fc.containmentStale = false
+
+	// Vertex 0 is the outside and each plot is the vertex after its index.
+	vertices := make([]int32, fc.nextEnclosure+1)
+	for i, plot := range fc.gardenPlots {
+		plot.parent = nil
+		plot.children = nil
+		plot.holePerimiter = 0
+		vertices[plot.Enclosure] = int32(i + 1)
+	}
+	numVertices := len(fc.gardenPlots) + 1
+
+	vertex := func(row int, column int) int32 {
+		if row < 0 || row >= len(fc.enclosures) || column < 0 || column >= len(fc.enclosures[row]) {
+			return 0
+		}
+		return vertices[fc.enclosures[row][column]]
+	}
+
+	// Every fence is one edge of the graph, so an edge can appear more than
+	// once, which does not change which regions separate others.
+	forEachFence := func(visit func(a int32, b int32)) {
+		for row := -1; row < len(fc.enclosures); row++ {
+			for column := -1; column < len(fc.enclosures[0]); column++ {
+				a := vertex(row, column)
+				for _, direction := range []Point{{Row: 0, Col: 1}, {Row: 1, Col: 0}} {
+					b := vertex(row+direction.Row, column+direction.Col)
+					if a != b {
+						visit(a, b)
+					}
+				}
+			}
+		}
+	}
+
+	starts := make([]int32, numVertices+1)
+	forEachFence(func(a int32, b int32) {
+		starts[a+1]++
+		starts[b+1]++
+	})
+	for v := 1; v <= numVertices; v++ {
+		starts[v] += starts[v-1]
+	}
+	edges := make([]int32, starts[numVertices])
+	filled := make([]int32, numVertices)
+	copy(filled, starts)
+	forEachFence(func(a int32, b int32) {
+		edges[filled[a]] = b
+		filled[a]++
+		edges[filled[b]] = a
+		filled[b]++
+	})
+
+	discovered := make([]int32, numVertices)
+	low := make([]int32, numVertices)
+	parents := make([]int32, numVertices)
+	for v := range discovered {
+		discovered[v] = -1
+	}
+
+	type frame struct {
+		vertex int32
+		edge   int32
+	}
+
+	preorder := make([]int32, 0, numVertices)
+	discovered[0], parents[0] = 0, -1
+	stack := []frame{{vertex: 0, edge: starts[0]}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		v := top.vertex
+
+		if top.edge < starts[v+1] {
+			w := edges[top.edge]
+			top.edge++
+
+			if discovered[w] == -1 {
+				discovered[w] = int32(len(preorder) + 1)
+				low[w] = discovered[w]
+				parents[w] = v
+				preorder = append(preorder, w)
+				stack = append(stack, frame{vertex: w, edge: starts[w]})
+			} else if w != parents[v] {
+				low[v] = min(low[v], discovered[w])
+			}
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		if parent := parents[v]; parent >= 0 {
+			low[parent] = min(low[parent], low[v])
+		}
+	}
+
+	// A region's parent in the search separates it from the outside when
+	// nothing below the region reaches back above the parent. Otherwise the
+	// innermost region enclosing it is the one enclosing its parent.
+	enclosers := make([]int32, numVertices)
+	for _, v := range preorder {
+		parent := parents[v]
+		if low[v] >= discovered[parent] {
+			enclosers[v] = parent
+		} else {
+			enclosers[v] = enclosers[parent]
+		}
+	}
+
+	for i, plot := range fc.gardenPlots {
+		if encloser := enclosers[i+1]; encloser != 0 {
+			plot.parent = fc.gardenPlots[encloser-1]
+			plot.parent.children = append(plot.parent.children, plot)
+		}
+	}
+
+	forEachFence(func(a int32, b int32) {
+		if a != 0 && enclosers[b] == a {
+			fc.gardenPlots[a-1].holePerimiter++
+		} else if b != 0 && enclosers[a] == b {
+			fc.gardenPlots[b-1].holePerimiter++
+		}
+	})
+}
+
+// refreshContainment builds the containment forest the first time it is needed
+// and again whenever a replant has left it out of date.
+func (fc *FencingCalculator) refreshContainment() {
+	if fc.containmentStale {
+		fc.ExamineContainment()
+	}
+}
+
+// Parent is the innermost region enclosing the plot, or nil if none does.
+func (fc *FencingCalculator) Parent(plot *GardenPlot) *GardenPlot {
+	fc.refreshContainment()
+	return plot.parent
+}
+
+// Children are the regions the plot is the innermost encloser of.
+func (fc *FencingCalculator) Children(plot *GardenPlot) []*GardenPlot {
+	fc.refreshContainment()
+	return plot.children
+}
+
+func (fc *FencingCalculator) IsIsland(plot *GardenPlot) bool {
+	return fc.Parent(plot) != nil
+}
+
+// HolePerimeter is the length of the plot's fences around the regions it
+// encloses.
+func (fc *FencingCalculator) HolePerimeter(plot *GardenPlot) int {
+	fc.refreshContainment()
+	return plot.holePerimiter
+}
+
+func (fc *FencingCalculator) OuterPerimeter(plot *GardenPlot) int {
+	return plot.perimiter - fc.HolePerimeter(plot)
+}
+
+func (fc *FencingCalculator) ContainmentForest() []*GardenPlot {
+	fc.refreshContainment()
+
+	roots := make([]*GardenPlot, 0)
+	for _, plot := range fc.gardenPlots {
+		if plot.parent == nil {
+			roots = append(roots, plot)
+		}
+	}
+
+	return roots
+}
+
+func (fc *FencingCalculator) Islands() []*GardenPlot {
+	fc.refreshContainment()
+
+	islands := make([]*GardenPlot, 0)
+	for _, plot := range fc.gardenPlots {
+		if plot.parent != nil {
+			islands = append(islands, plot)
+		}
+	}
+
+	return islands
+}
+
+func (fc *FencingCalculator) PrintContainmentForest() {
+	var printTree func(plot *GardenPlot, depth int)
+	printTree = func(plot *GardenPlot, depth int) {
+		fmt.Printf("%s%d %q area %d, outer perimeter %d, hole perimeter %d\n",
+			strings.Repeat("  ", depth), plot.Enclosure, plot.Plant, plot.area, fc.OuterPerimeter(plot), fc.HolePerimeter(plot))
+		for _, child := range fc.Children(plot) {
+			printTree(child, depth+1)
+		}
+	}
+
+	for _, root := range fc.ContainmentForest() {
+		printTree(root, 0)
+	}
+}
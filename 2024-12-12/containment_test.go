@@ -0,0 +1,194 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestContainmentDiamond(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{".X.", "X.X", ".X."}, "8")
+
+	roots := fencingCalculator.ContainmentForest()
+	if len(roots) != 2 {
+		t.Fatalf("expected the '.' and X regions to both be roots, got %d roots", len(roots))
+	}
+	for _, plot := range fencingCalculator.gardenPlots {
+		if plot.parent != nil || len(plot.children) > 0 || plot.holePerimiter != 0 {
+			t.Errorf("region %q of area %d should neither enclose nor be enclosed, has parent %v, %d children and hole perimeter %d",
+				plot.Plant, plot.area, plot.parent, len(plot.children), plot.holePerimiter)
+		}
+	}
+	if islands := fencingCalculator.Islands(); len(islands) != 0 {
+		t.Errorf("expected no islands, got %d", len(islands))
+	}
+}
+
+func TestContainmentDiamondWithCentre(t *testing.T) {
+	lines := []string{".X.", "XYX", ".X."}
+
+	fencingCalculator := examineGarden(t, lines, "8")
+	islands := fencingCalculator.Islands()
+	if len(islands) != 1 || islands[0].Plant != 'Y' {
+		t.Fatalf("expected Y to be the only island, got %d islands", len(islands))
+	}
+	if parent := fencingCalculator.Parent(islands[0]); parent.Plant != 'X' || fencingCalculator.HolePerimeter(parent) != 4 ||
+		fencingCalculator.OuterPerimeter(parent) != 12 {
+		t.Errorf("expected Y inside X with a hole perimeter of 4 and an outer perimeter of 12, got %q with %d and %d",
+			parent.Plant, fencingCalculator.HolePerimeter(parent), fencingCalculator.OuterPerimeter(parent))
+	}
+
+	// Joined orthogonally the X cells are four separate regions, none of
+	// which encloses Y on its own.
+	fencingCalculator = examineGarden(t, lines, "4")
+	if islands := fencingCalculator.Islands(); len(islands) != 0 {
+		t.Errorf("expected no islands with four-way connectivity, got %d", len(islands))
+	}
+}
+
+func TestContainmentNested(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{
+		"AAAAAAA",
+		"ABBBBBA",
+		"ABCCCBA",
+		"ABCDCBA",
+		"ABCCCBA",
+		"ABBBBBA",
+		"AAAAAAA",
+	}, "4")
+
+	roots := fencingCalculator.ContainmentForest()
+	if len(roots) != 1 {
+		t.Fatalf("expected one root, got %d", len(roots))
+	}
+
+	expected := []struct {
+		plant         rune
+		holePerimeter int
+	}{{'A', 20}, {'B', 12}, {'C', 4}, {'D', 0}}
+	plot := roots[0]
+	for depth, region := range expected {
+		if plot.Plant != region.plant || fencingCalculator.HolePerimeter(plot) != region.holePerimeter {
+			t.Fatalf("expected %q with hole perimeter %d at depth %d, got %q with %d",
+				region.plant, region.holePerimeter, depth, plot.Plant, fencingCalculator.HolePerimeter(plot))
+		}
+		if depth < len(expected)-1 {
+			children := fencingCalculator.Children(plot)
+			if len(children) != 1 {
+				t.Fatalf("expected %q to have one child, got %d", plot.Plant, len(children))
+			}
+			plot = children[0]
+		}
+	}
+}
+
+// enclosesByFlooding checks whether the region cannot reach the outside of the
+// garden without crossing the encloser, stepping orthogonally or through the
+// connectivity of the region a cell belongs to.
+func enclosesByFlooding(fc *FencingCalculator, encloser int, region int) bool {
+	rows, columns := len(fc.enclosures), len(fc.enclosures[0])
+	visited := make([]bool, rows*columns)
+	stack := make([]Point, 0)
+	for row := range fc.enclosures {
+		for column, enclosure := range fc.enclosures[row] {
+			if enclosure == region {
+				visited[row*columns+column] = true
+				stack = append(stack, Point{Row: row, Col: column})
+			}
+		}
+	}
+
+	for len(stack) > 0 {
+		point := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		for _, direction := range orthogonalDirections {
+			row, column := point.Row+direction.Row, point.Col+direction.Col
+			if row < 0 || row >= rows || column < 0 || column >= columns {
+				return false
+			}
+			if fc.enclosures[row][column] != encloser && !visited[row*columns+column] {
+				visited[row*columns+column] = true
+				stack = append(stack, Point{Row: row, Col: column})
+			}
+		}
+
+		enclosure := fc.enclosures[point.Row][point.Col]
+		for _, direction := range fc.connectivity {
+			row, column := point.Row+direction.Row, point.Col+direction.Col
+			if fc.InEnclosure(row, column, enclosure) && !visited[row*columns+column] {
+				visited[row*columns+column] = true
+				stack = append(stack, Point{Row: row, Col: column})
+			}
+		}
+	}
+
+	return true
+}
+
+func TestContainmentMatchesFlooding(t *testing.T) {
+	random := rand.New(rand.NewSource(12))
+	for _, connectivity := range []string{"4", "8", "0,2;1,1"} {
+		for i := 0; i < 200; i++ {
+			rows, columns := 1+random.Intn(9), 1+random.Intn(9)
+			lines := make([]string, rows)
+			for row := range lines {
+				plants := make([]byte, columns)
+				for column := range plants {
+					plants[column] = "ABC"[random.Intn(2+random.Intn(2))]
+				}
+				lines[row] = string(plants)
+			}
+
+			fencingCalculator := examineGarden(t, lines, connectivity)
+			fencingCalculator.ExamineContainment()
+
+			// The innermost encloser is the one enclosed by the most others.
+			for _, plot := range fencingCalculator.gardenPlots {
+				var expected *GardenPlot
+				depth := -1
+				for _, encloser := range fencingCalculator.gardenPlots {
+					if encloser == plot || !enclosesByFlooding(fencingCalculator, encloser.Enclosure, plot.Enclosure) {
+						continue
+					}
+
+					encloserDepth := 0
+					for _, outer := range fencingCalculator.gardenPlots {
+						if outer != encloser && enclosesByFlooding(fencingCalculator, outer.Enclosure, encloser.Enclosure) {
+							encloserDepth++
+						}
+					}
+					if encloserDepth > depth {
+						expected, depth = encloser, encloserDepth
+					}
+				}
+
+				if plot.parent != expected {
+					t.Fatalf("connectivity %s, garden %q: region %d has parent %v, expected %v",
+						connectivity, lines, plot.Enclosure, plot.parent, expected)
+				}
+			}
+		}
+	}
+}
+
+func TestContainmentIsNeverStale(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{"XXX", "XYX", "XXX"}, "4")
+
+	// Asked before the forest has been built, the accessors build it.
+	x, y := fencingCalculator.gardenPlots[0], fencingCalculator.gardenPlots[1]
+	if fencingCalculator.Parent(y) != x || fencingCalculator.HolePerimeter(x) != 4 || fencingCalculator.OuterPerimeter(x) != 12 {
+		t.Fatalf("expected Y inside X with a hole perimeter of 4 and an outer perimeter of 12, got parent %v with %d and %d",
+			fencingCalculator.Parent(y), fencingCalculator.HolePerimeter(x), fencingCalculator.OuterPerimeter(x))
+	}
+
+	// Opening the ring lets Y out, and the accessors rebuild the forest.
+	_, err := fencingCalculator.Replant(0, 1, 'Y')
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, plot := range fencingCalculator.gardenPlots {
+		if fencingCalculator.IsIsland(plot) || fencingCalculator.HolePerimeter(plot) != 0 ||
+			fencingCalculator.OuterPerimeter(plot) != plot.perimiter {
+			t.Errorf("region %q should not be enclosed or enclose anything after opening the ring", plot.Plant)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+type RegionChangeKind string
+
+const (
+	Appeared    RegionChangeKind = "appeared"
+	Disappeared RegionChangeKind = "disappeared"
+	Merged      RegionChangeKind = "merged"
+	Split       RegionChangeKind = "split"
+	Reshaped    RegionChangeKind = "reshaped"
+	Changed     RegionChangeKind = "changed"
+	Unchanged   RegionChangeKind = "unchanged"
+)
+
+// RegionChange groups regions from the two seasons that share cells of the
+// same plant, directly or through each other. A group with several regions on
+// both sides has had some merged and some split, and is counted as reshaped.
+type RegionChange struct {
+	Kind   RegionChangeKind
+	Plant  rune
+	Before []*GardenPlot
+	After  []*GardenPlot
+}
+
+func (rc RegionChange) AreaChange() int {
+	change := 0
+	for _, plot := range rc.After {
+		change += plot.area
+	}
+	for _, plot := range rc.Before {
+		change -= plot.area
+	}
+
+	return change
+}
+
+func (rc RegionChange) PerimeterChange() int {
+	change := 0
+	for _, plot := range rc.After {
+		change += plot.perimiter
+	}
+	for _, plot := range rc.Before {
+		change -= plot.perimiter
+	}
+
+	return change
+}
+
+func (rc RegionChange) PriceChange(strategy PricingStrategy) float64 {
+	return ReplantResult{Removed: rc.Before, Added: rc.After}.PriceChange(strategy)
+}
+
+// DiffGardens compares two examined gardens of the same shape. Every region
+// ends up in exactly one change, so the price changes add up to the change in
+// the total price.
+func DiffGardens(before *FencingCalculator, after *FencingCalculator) ([]RegionChange, error) {
+	if len(before.garden) != len(after.garden) || len(before.garden[0]) != len(after.garden[0]) {
+		return nil, fmt.Errorf("gardens have different shapes: %dx%d and %dx%d",
+			len(before.garden), len(before.garden[0]), len(after.garden), len(after.garden[0]))
+	}
+
+	// Before regions are numbered from 0 in the disjoint-set and after regions
+	// follow them, so the overlaps join the two seasons' regions into groups.
+	numBefore := len(before.gardenPlots)
+	disjointSet := NewDisjointSet(numBefore + len(after.gardenPlots))
+
+	beforeIndexes := make(map[int]int)
+	for i, plot := range before.gardenPlots {
+		beforeIndexes[plot.Enclosure] = i
+	}
+	afterIndexes := make(map[int]int)
+	for i, plot := range after.gardenPlots {
+		afterIndexes[plot.Enclosure] = numBefore + i
+	}
+
+	for row := range before.garden {
+		for column, plant := range before.garden[row] {
+			if after.garden[row][column] != plant {
+				continue
+			}
+
+			disjointSet.Union(beforeIndexes[before.enclosures[row][column]], afterIndexes[after.enclosures[row][column]])
+		}
+	}
+
+	groups := make(map[int]*RegionChange)
+	roots := make([]int, 0)
+	group := func(i int, plant rune) *RegionChange {
+		root := disjointSet.Find(i)
+		change, ok := groups[root]
+		if !ok {
+			change = &RegionChange{Plant: plant}
+			groups[root] = change
+			roots = append(roots, root)
+		}
+		return change
+	}
+
+	for i, plot := range before.gardenPlots {
+		change := group(i, plot.Plant)
+		change.Before = append(change.Before, plot)
+	}
+	for i, plot := range after.gardenPlots {
+		change := group(numBefore+i, plot.Plant)
+		change.After = append(change.After, plot)
+	}
+
+	// Groups come in the order of their first before region, followed by the
+	// regions that only exist afterwards.
+	changes := make([]RegionChange, 0, len(roots))
+	for _, root := range roots {
+		change := groups[root]
+		switch {
+		case len(change.Before) == 0:
+			change.Kind = Appeared
+		case len(change.After) == 0:
+			change.Kind = Disappeared
+		case len(change.Before) > 1 && len(change.After) > 1:
+			change.Kind = Reshaped
+		case len(change.Before) > 1:
+			change.Kind = Merged
+		case len(change.After) > 1:
+			change.Kind = Split
+		default:
+			beforePlot, afterPlot := change.Before[0], change.After[0]
+			if beforePlot.area != afterPlot.area || beforePlot.perimiter != afterPlot.perimiter || beforePlot.sides != afterPlot.sides {
+				change.Kind = Changed
+			} else {
+				change.Kind = Unchanged
+			}
+		}
+		changes = append(changes, *change)
+	}
+
+	return changes, nil
+}
+
+func enclosureList(plots []*GardenPlot) string {
+	if len(plots) == 0 {
+		return "-"
+	}
+
+	ids := make([]string, 0, len(plots))
+	for _, plot := range plots {
+		ids = append(ids, strconv.Itoa(plot.Enclosure))
+	}
+
+	return strings.Join(ids, ",")
+}
+
+// WriteGardenDiff lists every change other than regions that stayed the same,
+// then the change in the total price.
+func WriteGardenDiff(w io.Writer, changes []RegionChange, strategy PricingStrategy) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprint(writer, "change\tplant\tbefore\tafter\tarea\tperimeter\tprice\t\n")
+
+	unchanged := 0
+	total := 0.0
+	for _, change := range changes {
+		if change.Kind == Unchanged {
+			unchanged++
+			continue
+		}
+
+		priceChange := change.PriceChange(strategy)
+		total += priceChange
+		fmt.Fprintf(writer, "%s\t%c\t%s\t%s\t%+d\t%+d\t%+.2f\t\n", change.Kind, change.Plant,
+			enclosureList(change.Before), enclosureList(change.After),
+			change.AreaChange(), change.PerimeterChange(), priceChange)
+	}
+
+	fmt.Fprintf(writer, "%d regions unchanged\n", unchanged)
+	fmt.Fprintf(writer, "Fencing price by %s changed by %+.2f\n", strategy.Name(), total)
+
+	return writer.Flush()
+}
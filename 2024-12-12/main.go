@@ -4,7 +4,12 @@ import (
 	"bufio"
 	"flag"
 	"fmt"
+	"io"
+	"math"
 	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
 )
 
 func ParseInputFile(path string) []string {
@@ -15,26 +20,319 @@ func ParseInputFile(path string) []string {
 	}
 	defer file.Close()
 
+	lines, err := ReadGarden(file)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+	return lines
+}
+
+// ReadGarden reads the garden's rows. Blank lines may only trail the garden, as
+// a blank row in the middle would otherwise quietly cut the garden short.
+func ReadGarden(r io.Reader) ([]string, error) {
 	var lines []string
-	scanner := bufio.NewScanner(file)
+	blankLine := 0
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), math.MaxInt32)
 	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+		lineNumber++
+		line := scanner.Text()
+		if len(line) <= 0 {
+			if blankLine == 0 {
+				blankLine = lineNumber
+			}
+			continue
+		}
+
+		if blankLine != 0 {
+			return nil, fmt.Errorf("garden has a blank row at line %d", blankLine)
+		}
+		lines = append(lines, line)
 	}
-	return lines
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return lines, nil
+}
+
+// StreamInputFile prices the garden at the path without loading it, where
+// "-" reads from stdin. Only the CSV report can be written as regions finish.
+func StreamInputFile(path string, report string) {
+	if report != "" && report != "csv" {
+		fmt.Printf("the %s report cannot be streamed, use csv\n", report)
+		panic(0)
+	}
+
+	file := os.Stdin
+	if path != "-" {
+		var err error
+		file, err = os.Open(path)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		defer file.Close()
+	}
+
+	var reportWriter io.Writer
+	if report != "" {
+		reportWriter = os.Stdout
+	}
+
+	_, totalPrice, totalBulkPrice, err := StreamTotals(bufio.NewReader(file), reportWriter)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	if report == "" {
+		fmt.Println("Total fencing price: ", totalPrice)
+		fmt.Println("Total fencing price with bulk discount: ", totalBulkPrice)
+	}
+}
+
+// streamableFlags are the only flags that still mean something when the garden
+// is streamed, as everything else needs the whole garden at once.
+var streamableFlags = map[string]bool{"path": true, "stream": true, "report": true}
+
+// CheckStreamFlags rejects any flag given with -stream that streaming would
+// otherwise silently ignore, unless it is left at its default.
+func CheckStreamFlags() error {
+	unsupported := make([]string, 0)
+	flag.Visit(func(f *flag.Flag) {
+		if !streamableFlags[f.Name] && f.Value.String() != f.DefValue {
+			unsupported = append(unsupported, "-"+f.Name)
+		}
+	})
+
+	if len(unsupported) > 0 {
+		return fmt.Errorf("%s cannot be used with -stream", strings.Join(unsupported, ", "))
+	}
+
+	return nil
 }
 
 func main() {
 	var path string
 	flag.StringVar(&path, "path", "", "The path to the input file")
 
+	var connectivity string
+	flag.StringVar(&connectivity, "connectivity", "4", "Which neighbours join a region: 4, 8, or a custom stencil such as \"-1,0;1,0;0,2;0,-2\"")
+
+	var outline string
+	flag.StringVar(&outline, "outline", "", "Print every region's fence outline in the given format (geojson or wkt)")
+
+	var svgPath string
+	flag.StringVar(&svgPath, "svg", "", "Render the garden with its regions and fences to an SVG file at the given path")
+
+	var pngPath string
+	flag.StringVar(&pngPath, "png", "", "Render the garden with its regions and fences to a PNG file at the given path")
+
+	var cellSize int
+	flag.IntVar(&cellSize, "cell-size", 16, "The size in pixels of each garden cell when rendering")
+
+	var labels bool
+	flag.BoolVar(&labels, "labels", false, "Label each rendered region with its enclosure id and fencing price")
+
+	var pricing string
+	flag.StringVar(&pricing, "pricing", "perimeter", "How fencing is priced in the budget report, garden diff, replants and replant optimiser (perimeter or sides)")
+
+	var rates string
+	flag.StringVar(&rates, "rates", "", "The path to a JSON table of per-plant cost multipliers for the budget report")
+
+	var budget float64
+	flag.Float64Var(&budget, "budget", 0, "The fencing budget to compare the total price against in the budget report")
+
+	var replant string
+	flag.StringVar(&replant, "replant", "", "Replant cells before pricing, as semicolon separated row,column,plant triples")
+
+	var tree bool
+	flag.BoolVar(&tree, "tree", false, "Print the forest of regions enclosed by other regions")
+
+	var report string
+	flag.StringVar(&report, "report", "", "Print a per-region report in the given format (table, csv or json)")
+
+	var stream bool
+	flag.BoolVar(&stream, "stream", false, "Read the garden a row at a time, for gardens too big to hold in memory (use -path - for stdin)")
+
+	var diff string
+	flag.StringVar(&diff, "diff", "", "Compare the garden with next season's garden at the given path and report how its regions changed")
+
+	var optimise int
+	flag.IntVar(&optimise, "optimise", 0, "List the given number of single cell replants that lower the fencing price the most")
+
+	var workers int
+	flag.IntVar(&workers, "workers", 1, "The number of goroutines to label horizontal strips of the garden with")
+
 	flag.Parse()
 
-	fmt.Println(path)
+	if report == "" && outline == "" {
+		fmt.Println(path)
+	}
+
+	if stream {
+		err := CheckStreamFlags()
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		StreamInputFile(path, report)
+		return
+	}
 
 	garden := ParseInputFile(path)
-	fencingCalculator := NewFencingCalculator(garden)
-	fencingCalculator.ExamineGarden()
+	fencingCalculator, err := NewFencingCalculator(garden)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	stencil, err := ParseConnectivity(connectivity)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+	fencingCalculator.SetConnectivity(stencil)
+
+	fencingCalculator.ExamineGardenParallel(workers)
+
+	if replant != "" {
+		replantings, err := ParseReplantings(replant)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		strategy, err := LoadPricingStrategy(pricing, rates)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		for _, replanting := range replantings {
+			result, err := fencingCalculator.Replant(replanting.Row, replanting.Column, replanting.Plant)
+			if err != nil {
+				fmt.Println(err)
+				panic(0)
+			}
+
+			if report == "" && outline == "" {
+				fmt.Printf("Replanted %d,%d with %q, fencing price by %s changed by %+.2f\n",
+					replanting.Row, replanting.Column, replanting.Plant, strategy.Name(), result.PriceChange(strategy))
+			}
+		}
+	}
+
+	if tree {
+		fencingCalculator.PrintContainmentForest()
+	}
+
+	if svgPath != "" {
+		err := fencingCalculator.RenderSvgFile(svgPath, cellSize, labels)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+
+	if pngPath != "" {
+		err := fencingCalculator.RenderPngFile(pngPath, cellSize, labels)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
+
+	if optimise > 0 {
+		strategy, err := LoadPricingStrategy(pricing, rates)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		candidates, err := fencingCalculator.FindCheapestReplants(strategy, optimise)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		err = WriteReplantCandidates(os.Stdout, candidates, strategy)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		return
+	}
+
+	if diff != "" {
+		nextSeason, err := NewFencingCalculator(ParseInputFile(diff))
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		nextSeason.SetConnectivity(stencil)
+		nextSeason.ExamineGardenParallel(workers)
+
+		changes, err := DiffGardens(fencingCalculator, nextSeason)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		strategy, err := LoadPricingStrategy(pricing, rates)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		err = WriteGardenDiff(os.Stdout, changes, strategy)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		return
+	}
+
+	if outline != "" {
+		err := fencingCalculator.WriteOutlines(os.Stdout, outline)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		return
+	}
+
+	if report != "" {
+		err := WriteGardenReport(os.Stdout, report, fencingCalculator.Report())
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+		return
+	}
+
 	fmt.Println("Total fencing price: ", fencingCalculator.CalculateTotalFencingPrice())
+	fmt.Println("Total fencing price with bulk discount: ", fencingCalculator.CalculateTotalBulkPrice())
+
+	if rates != "" || budget > 0 || pricing != "perimeter" {
+		strategy, err := LoadPricingStrategy(pricing, rates)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+
+		fmt.Println()
+		err = fencingCalculator.WriteBudgetReport(os.Stdout, strategy, budget)
+		if err != nil {
+			fmt.Println(err)
+			panic(0)
+		}
+	}
 }
 
 type GardenPlot struct {
@@ -43,123 +341,295 @@ type GardenPlot struct {
 
 	area      int
 	perimiter int
+	sides     int
+
+	bounds    BoundingBox
+	rowSum    int
+	columnSum int
+
+	parent        *GardenPlot
+	children      []*GardenPlot
+	holePerimiter int
+}
+
+type BoundingBox struct {
+	Top    int `json:"top"`
+	Left   int `json:"left"`
+	Bottom int `json:"bottom"`
+	Right  int `json:"right"`
+}
+
+func (gp *GardenPlot) AddCell(point Point) {
+	if gp.area == 0 {
+		gp.bounds = BoundingBox{Top: point.Row, Left: point.Col, Bottom: point.Row, Right: point.Col}
+	} else {
+		gp.bounds.Top = min(gp.bounds.Top, point.Row)
+		gp.bounds.Left = min(gp.bounds.Left, point.Col)
+		gp.bounds.Bottom = max(gp.bounds.Bottom, point.Row)
+		gp.bounds.Right = max(gp.bounds.Right, point.Col)
+	}
+
+	gp.area += 1
+	gp.rowSum += point.Row
+	gp.columnSum += point.Col
+}
+
+func (gp *GardenPlot) Centroid() (float64, float64) {
+	return float64(gp.rowSum) / float64(gp.area), float64(gp.columnSum) / float64(gp.area)
 }
 
 func (gp *GardenPlot) CalculateFencingPrice() int {
 	return gp.area * gp.perimiter
 }
 
+func (gp *GardenPlot) CalculateBulkFencingPrice() int {
+	return gp.area * gp.sides
+}
+
 type FencingCalculator struct {
-	garden      []string
-	inspected   [][]bool
-	gardenPlots []*GardenPlot
+	garden       [][]rune
+	connectivity []Point
+	enclosures   [][]int
+	gardenPlots  []*GardenPlot
+
+	nextEnclosure    int
+	containmentStale bool
 }
 
-func NewFencingCalculator(garden []string) *FencingCalculator {
+func NewFencingCalculator(lines []string) (*FencingCalculator, error) {
+	garden, err := ParseGarden(lines)
+	if err != nil {
+		return nil, err
+	}
+
 	numRows := len(garden)
 	numColumns := len(garden[0])
 
-	inspected := [][]bool{}
-
-	for row := 0; row < numRows; row++ {
-		inspected = append(inspected, make([]bool, numColumns))
+	cells := make([]int, numRows*numColumns)
+	enclosures := make([][]int, numRows)
+	for row := range enclosures {
+		enclosures[row] = cells[row*numColumns : (row+1)*numColumns]
 	}
 
 	gardenPlots := make([]*GardenPlot, 0)
 
 	return &FencingCalculator{
-		garden:      garden,
-		inspected:   inspected,
-		gardenPlots: gardenPlots,
+		garden:       garden,
+		connectivity: orthogonalDirections,
+		enclosures:   enclosures,
+		gardenPlots:  gardenPlots,
+	}, nil
+}
+
+// ParseGarden splits each line into runes, so any single Unicode character,
+// accented letters and emoji included, can mark a plant. Characters that only
+// make sense joined to the ones around them are rejected rather than split off.
+func ParseGarden(lines []string) ([][]rune, error) {
+	garden := make([][]rune, 0, len(lines))
+	for row, line := range lines {
+		plants, err := ParseGardenRow(line, row)
+		if err != nil {
+			return nil, err
+		}
+		garden = append(garden, plants)
+	}
+
+	err := ValidateGarden(garden)
+	if err != nil {
+		return nil, err
 	}
+
+	return garden, nil
 }
 
-func (fc *FencingCalculator) ExamineGarden() {
-	numRows := len(fc.garden)
-	numColumns := len(fc.garden[0])
+func ParseGardenRow(line string, row int) ([]rune, error) {
+	if !utf8.ValidString(line) {
+		return nil, fmt.Errorf("garden row %d is not valid UTF-8", row+1)
+	}
+
+	plants := []rune(line)
+	for column, plant := range plants {
+		err := ValidatePlant(plant)
+		if err != nil {
+			return nil, fmt.Errorf("garden row %d, column %d: %w", row+1, column+1, err)
+		}
+	}
 
-	inspected := [][]bool{}
+	return plants, nil
+}
 
-	for row := 0; row < numRows; row++ {
-		inspected = append(inspected, make([]bool, numColumns))
+// ValidatePlant rejects characters that only make sense joined to the ones
+// around them, such as combining accents, the variation selector in ❤️, the
+// zero width joiner in 👩‍🌾, skin tones and the halves of a flag. Split into
+// runes they would each become a plant of their own, most of them invisible.
+func ValidatePlant(plant rune) error {
+	switch {
+	case unicode.Is(unicode.Variation_Selector, plant):
+		return fmt.Errorf("%U is a variation selector, so it cannot be a plant on its own", plant)
+	case unicode.Is(unicode.Mark, plant):
+		return fmt.Errorf("%U is a combining mark, so it cannot be a plant on its own", plant)
+	case unicode.Is(unicode.Join_Control, plant):
+		return fmt.Errorf("%U joins the characters around it, so it cannot be a plant on its own", plant)
+	case unicode.Is(unicode.Regional_Indicator, plant):
+		return fmt.Errorf("%U is half of a flag, so it cannot be a plant on its own", plant)
+	case plant >= 0x1F3FB && plant <= 0x1F3FF:
+		return fmt.Errorf("%U is a skin tone modifier, so it cannot be a plant on its own", plant)
+	case plant >= 0xE0020 && plant <= 0xE007F:
+		return fmt.Errorf("%U is a tag character, so it cannot be a plant on its own", plant)
 	}
 
+	return nil
+}
+
+func ValidateGarden(garden [][]rune) error {
+	if len(garden) == 0 || len(garden[0]) == 0 {
+		return fmt.Errorf("garden is empty")
+	}
+
+	numColumns := len(garden[0])
+	for row, plants := range garden {
+		if len(plants) != numColumns {
+			return fmt.Errorf("garden is ragged: row %d has %d plants but row 1 has %d", row+1, len(plants), numColumns)
+		}
+	}
+
+	return nil
+}
+
+// ExamineGarden labels and measures every region in one raster pass. A cell's
+// whole region is labelled by the time the pass reaches it, so its fences and
+// corners can be measured straight away.
+func (fc *FencingCalculator) ExamineGarden() {
 	enclosureId := 0
-	for row := 0; row < numRows; row++ {
-		for column := 0; column < numColumns; column++ {
-			if !fc.inspected[row][column] {
+	queue := make([]Point, 0)
+	for row, plants := range fc.garden {
+		for column, plant := range plants {
+			if fc.enclosures[row][column] == 0 {
 				enclosureId++
-				plant := rune(fc.garden[row][column])
-				plot := &GardenPlot{
+				fc.gardenPlots = append(fc.gardenPlots, &GardenPlot{
 					Plant:     plant,
 					Enclosure: enclosureId,
-				}
-				fc.gardenPlots = append(fc.gardenPlots, plot)
-				fc.FloodFill(row, column, plant, plot)
+				})
+				queue = fc.floodFill(row, column, plant, enclosureId, queue)
 			}
+
+			fc.MeasureCell(fc.gardenPlots[fc.enclosures[row][column]-1], row, column)
 		}
 	}
+
+	fc.nextEnclosure = enclosureId
+	fc.containmentStale = true
 }
 
-func (fc *FencingCalculator) FloodFill(row int, column int, plant rune, plot *GardenPlot) {
-	if fc.inspected[row][column] {
-		return
-	}
+type Point struct {
+	Row int
+	Col int
+}
 
-	if fc.garden[row][column] != byte(plant) {
-		return
+var orthogonalDirections = []Point{{Row: -1, Col: 0}, {Row: 0, Col: 1}, {Row: 1, Col: 0}, {Row: 0, Col: -1}}
+
+// FloodFill labels the region with the enclosure id, working through a queue
+// of cells still to visit rather than recursing, so the size of a region is
+// limited by memory and not by the goroutine stack. Visiting cells in the order
+// they are found keeps the queue to the region's frontier, where a stack can
+// grow nearly as large as the region. Cells join the region through the
+// calculator's connectivity. It only labels the region, which is measured a
+// cell at a time with MeasureCell.
+func (fc *FencingCalculator) FloodFill(row int, column int, plant rune, enclosure int) {
+	fc.floodFill(row, column, plant, enclosure, nil)
+}
+
+// floodFill reuses the queue it is given and hands it back, so examining a
+// garden of many small regions does not allocate one for each.
+func (fc *FencingCalculator) floodFill(row int, column int, plant rune, enclosure int, queue []Point) []Point {
+	if fc.enclosures[row][column] != 0 || !fc.HasPlant(row, column, plant) {
+		return queue
 	}
 
-	fc.inspected[row][column] = true
+	fc.enclosures[row][column] = enclosure
+	queue = append(queue[:0], Point{Row: row, Col: column})
 
-	plot.area += 1
+	for head := 0; head < len(queue); {
+		point := queue[head]
+		head++
 
-	perimiterContribution := 0
-	if row == 0 {
-		perimiterContribution += 1
-	} else {
-		plantAbove := fc.garden[row-1][column]
-		if plantAbove != byte(plant) {
-			perimiterContribution += 1
-		} else {
-			fc.FloodFill(row-1, column, plant, plot)
+		// Once most of the queue has been visited, the cells still to visit
+		// are moved to the front so its space can be used again.
+		if head >= 1024 && head*2 >= len(queue) {
+			queue = queue[:copy(queue, queue[head:])]
+			head = 0
 		}
-	}
 
-	if column+1 == len(fc.garden) {
-		perimiterContribution += 1
-	} else {
-		plantToRight := fc.garden[row][column+1]
-		if plantToRight != byte(plant) {
-			perimiterContribution += 1
-		} else {
-			fc.FloodFill(row, column+1, plant, plot)
+		for _, direction := range fc.connectivity {
+			neighbour := Point{Row: point.Row + direction.Row, Col: point.Col + direction.Col}
+			if fc.HasPlant(neighbour.Row, neighbour.Col, plant) && fc.enclosures[neighbour.Row][neighbour.Col] == 0 {
+				fc.enclosures[neighbour.Row][neighbour.Col] = enclosure
+				queue = append(queue, neighbour)
+			}
 		}
 	}
 
-	if row+1 == len(fc.garden[0]) {
-		perimiterContribution += 1
-	} else {
-		plantBelow := fc.garden[row+1][column]
-		if plantBelow != byte(plant) {
-			perimiterContribution += 1
-		} else {
-			fc.FloodFill(row+1, column, plant, plot)
+	return queue
+}
+
+// MeasureCell adds the cell to its labelled region's plot, along with the
+// fences on its orthogonal edges and the corners it sits on.
+func (fc *FencingCalculator) MeasureCell(plot *GardenPlot, row int, column int) {
+	plot.AddCell(Point{Row: row, Col: column})
+
+	for _, direction := range orthogonalDirections {
+		if !fc.InEnclosure(row+direction.Row, column+direction.Col, plot.Enclosure) {
+			plot.perimiter += 1
 		}
 	}
 
-	if column == 0 {
-		perimiterContribution += 1
-	} else {
-		plantToLeft := fc.garden[row][column-1]
-		if plantToLeft != byte(plant) {
-			perimiterContribution += 1
-		} else {
-			fc.FloodFill(row, column-1, plant, plot)
+	plot.sides += fc.CountCorners(row, column, plot.Enclosure)
+}
+
+// A region has as many straight sides as it has corners, including the corners
+// of any holes inside it, so each cell adds the corners it sits on.
+func (fc *FencingCalculator) CountCorners(row int, column int, enclosure int) int {
+	return CountCorners(row, column, func(row int, column int) bool {
+		return fc.InEnclosure(row, column, enclosure)
+	})
+}
+
+// CountCorners counts the corners of the cell's region that the cell sits on,
+// given a test for whether any other cell is in the same region.
+func CountCorners(row int, column int, inRegion func(row int, column int) bool) int {
+	corners := 0
+	for _, diagonal := range [][2]int{{-1, -1}, {-1, 1}, {1, 1}, {1, -1}} {
+		vertical := inRegion(row+diagonal[0], column)
+		horizontal := inRegion(row, column+diagonal[1])
+
+		if !vertical && !horizontal {
+			// . .
+			// X .
+			corners++
+		} else if vertical && horizontal && !inRegion(row+diagonal[0], column+diagonal[1]) {
+			// X .
+			// X X
+			corners++
 		}
 	}
 
-	plot.perimiter += perimiterContribution
+	return corners
+}
+
+func (fc *FencingCalculator) InEnclosure(row int, column int, enclosure int) bool {
+	if row < 0 || row >= len(fc.enclosures) || column < 0 || column >= len(fc.enclosures[row]) {
+		return false
+	}
+
+	return fc.enclosures[row][column] == enclosure
+}
+
+func (fc *FencingCalculator) HasPlant(row int, column int, plant rune) bool {
+	if row < 0 || row >= len(fc.garden) || column < 0 || column >= len(fc.garden[row]) {
+		return false
+	}
+
+	return fc.garden[row][column] == plant
 }
 
 func (fc *FencingCalculator) CalculateTotalFencingPrice() int {
@@ -170,3 +640,12 @@ func (fc *FencingCalculator) CalculateTotalFencingPrice() int {
 
 	return totalPrice
 }
+
+func (fc *FencingCalculator) CalculateTotalBulkPrice() int {
+	totalPrice := 0
+	for _, plot := range fc.gardenPlots {
+		totalPrice += plot.CalculateBulkFencingPrice()
+	}
+
+	return totalPrice
+}
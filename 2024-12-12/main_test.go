@@ -0,0 +1,200 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+func examineGarden(t testing.TB, lines []string, connectivity string) *FencingCalculator {
+	t.Helper()
+
+	fencingCalculator, err := NewFencingCalculator(lines)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stencil, err := ParseConnectivity(connectivity)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fencingCalculator.SetConnectivity(stencil)
+	fencingCalculator.ExamineGarden()
+
+	return fencingCalculator
+}
+
+func TestGardenShapes(t *testing.T) {
+	tests := []struct {
+		name      string
+		lines     []string
+		price     int
+		bulkPrice int
+	}{
+		{"single cell", []string{"A"}, 4, 4},
+		{"one row", []string{"AABBA"}, 28, 20},
+		{"one column", []string{"A", "A", "B", "B", "A"}, 28, 20},
+		{"wide", []string{"AAAAAA", "ABBBBA"}, 184, 80},
+		{"tall", []string{"AA", "AB", "AB", "AB", "AB", "AA"}, 184, 80},
+		{"example", []string{"AAAA", "BBCD", "BBCC", "EEEC"}, 140, 80},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fencingCalculator := examineGarden(t, test.lines, "4")
+			if price := fencingCalculator.CalculateTotalFencingPrice(); price != test.price {
+				t.Errorf("expected a fencing price of %d, got %d", test.price, price)
+			}
+			if bulkPrice := fencingCalculator.CalculateTotalBulkPrice(); bulkPrice != test.bulkPrice {
+				t.Errorf("expected a bulk fencing price of %d, got %d", test.bulkPrice, bulkPrice)
+			}
+
+			_, price, bulkPrice, err := StreamTotals(strings.NewReader(strings.Join(test.lines, "\n")+"\n"), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if price != test.price || bulkPrice != test.bulkPrice {
+				t.Errorf("streaming gave prices %d and %d, expected %d and %d", price, bulkPrice, test.price, test.bulkPrice)
+			}
+		})
+	}
+}
+
+func TestRaggedGardensAreRejected(t *testing.T) {
+	for _, lines := range [][]string{{"AAA", "AA"}, {"A", "AB"}, {}, {""}} {
+		_, err := NewFencingCalculator(lines)
+		if err == nil {
+			t.Errorf("expected garden %q to be rejected", lines)
+		}
+
+		err = StreamGarden(strings.NewReader(strings.Join(lines, "\n")), func(*GardenPlot) error { return nil })
+		if err == nil {
+			t.Errorf("expected streamed garden %q to be rejected", lines)
+		}
+	}
+}
+
+func TestBlankRows(t *testing.T) {
+	for _, input := range []string{"AAA\n\nBBB\nBBB\n", "\nAAA\n", "AAA\n\n\nB\n"} {
+		_, err := ReadGarden(strings.NewReader(input))
+		if err == nil {
+			t.Errorf("expected the blank row in %q to be rejected", input)
+		}
+
+		err = StreamGarden(strings.NewReader(input), func(*GardenPlot) error { return nil })
+		if err == nil {
+			t.Errorf("expected the blank row in streamed %q to be rejected", input)
+		}
+	}
+
+	lines, err := ReadGarden(strings.NewReader("AAA\nBBB\n\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(lines) != 2 {
+		t.Errorf("expected trailing blank lines to be dropped, got %q", lines)
+	}
+}
+
+func TestMixedWidthPlants(t *testing.T) {
+	// One, two, three and four byte plants in the same garden price the same
+	// as the ASCII garden with the same layout.
+	ascii := []string{"AABB", "ACCB", "DDCB", "DDAA"}
+	mixed := []string{"AAéé", "A漢漢é", "🌻🌻漢é", "🌻🌻AA"}
+
+	expected := examineGarden(t, ascii, "4")
+	actual := examineGarden(t, mixed, "4")
+	if actual.CalculateTotalFencingPrice() != expected.CalculateTotalFencingPrice() ||
+		actual.CalculateTotalBulkPrice() != expected.CalculateTotalBulkPrice() {
+		t.Errorf("expected prices %d and %d, got %d and %d",
+			expected.CalculateTotalFencingPrice(), expected.CalculateTotalBulkPrice(),
+			actual.CalculateTotalFencingPrice(), actual.CalculateTotalBulkPrice())
+	}
+
+	plants := make([]rune, 0)
+	for _, plot := range actual.gardenPlots {
+		plants = append(plants, plot.Plant)
+	}
+	if string(plants) != "Aé漢🌻A" {
+		t.Errorf("expected regions of A, é, 漢, 🌻 and A, got %q", string(plants))
+	}
+
+	_, price, bulkPrice, err := StreamTotals(strings.NewReader(strings.Join(mixed, "\n")), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != expected.CalculateTotalFencingPrice() || bulkPrice != expected.CalculateTotalBulkPrice() {
+		t.Errorf("streaming gave prices %d and %d, expected %d and %d",
+			price, bulkPrice, expected.CalculateTotalFencingPrice(), expected.CalculateTotalBulkPrice())
+	}
+}
+
+func TestPlantsJoinedToOtherCharactersAreRejected(t *testing.T) {
+	for _, line := range []string{
+		"\u2764\ufe0f\u2764\ufe0f",    // hearts, each followed by a variation selector
+		"e\u0301A",                    // e followed by a combining acute accent
+		"\U0001f469\u200d\U0001f33eA", // woman and sheaf of rice joined into a farmer
+		"\U0001f1ec\U0001f1e7",        // the two regional indicators of a flag
+		"\U0001f44d\U0001f3fdA",       // thumbs up with a skin tone
+	} {
+		_, err := NewFencingCalculator([]string{line})
+		if err == nil {
+			t.Errorf("expected garden %q to be rejected", line)
+		}
+
+		err = StreamGarden(strings.NewReader(line), func(*GardenPlot) error { return nil })
+		if err == nil {
+			t.Errorf("expected streamed garden %q to be rejected", line)
+		}
+	}
+
+	_, err := ParseReplantings("0,0,\u0301")
+	if err == nil {
+		t.Errorf("expected replanting with a combining accent to be rejected")
+	}
+}
+
+// largeGardenSide gives the benchmark gardens just over 10^7 cells.
+const largeGardenSide = 3163
+
+func largeGarden(plantAt func(row int, column int) byte) []string {
+	lines := make([]string, largeGardenSide)
+	for row := range lines {
+		line := make([]byte, largeGardenSide)
+		for column := range line {
+			line[column] = plantAt(row, column)
+		}
+		lines[row] = string(line)
+	}
+
+	return lines
+}
+
+func BenchmarkExamineGarden(b *testing.B) {
+	random := rand.New(rand.NewSource(35))
+	gardens := []struct {
+		name  string
+		lines []string
+	}{
+		// One region covering the whole garden is the deepest flood fill.
+		{"single plant", largeGarden(func(int, int) byte { return 'A' })},
+		// Nested L shapes give long, thin regions wrapped round each other.
+		{"nested", largeGarden(func(row int, column int) byte { return "AB"[max(row, column)%2] })},
+		{"random", largeGarden(func(int, int) byte { return byte('A' + random.Intn(4)) })},
+	}
+
+	for _, garden := range gardens {
+		b.Run(garden.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				fencingCalculator, err := NewFencingCalculator(garden.lines)
+				if err != nil {
+					b.Fatal(err)
+				}
+				b.StartTimer()
+
+				fencingCalculator.ExamineGarden()
+			}
+		})
+	}
+}
@@ -0,0 +1,336 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"text/tabwriter"
+)
+
+type ReplantCandidate struct {
+	Row         int
+	Column      int
+	From        rune
+	To          rune
+	NewPlant    bool
+	PriceChange float64
+}
+
+// Clone copies the garden and its regions so they can be replanted without
+// touching the original. The containment forest is rebuilt when it is next
+// needed, as the copied plots are not linked to each other.
+func (fc *FencingCalculator) Clone() *FencingCalculator {
+	numRows, numColumns := len(fc.garden), len(fc.garden[0])
+
+	garden := make([][]rune, numRows)
+	for row := range garden {
+		garden[row] = slices.Clone(fc.garden[row])
+	}
+
+	cells := make([]int, numRows*numColumns)
+	enclosures := make([][]int, numRows)
+	for row := range enclosures {
+		enclosures[row] = cells[row*numColumns : (row+1)*numColumns]
+		copy(enclosures[row], fc.enclosures[row])
+	}
+
+	gardenPlots := make([]*GardenPlot, 0, len(fc.gardenPlots))
+	for _, plot := range fc.gardenPlots {
+		clone := *plot
+		clone.parent = nil
+		clone.children = nil
+		clone.holePerimiter = 0
+		gardenPlots = append(gardenPlots, &clone)
+	}
+
+	return &FencingCalculator{
+		garden:           garden,
+		connectivity:     fc.connectivity,
+		enclosures:       enclosures,
+		gardenPlots:      gardenPlots,
+		nextEnclosure:    fc.nextEnclosure,
+		containmentStale: true,
+	}
+}
+
+// FindCheapestReplants tries replanting every cell with each plant it can
+// join, along with one plant that grows nowhere in the garden, or with every
+// plant when a rate table prices them differently, and returns the top
+// candidates that lower the price the most. Most candidates are scored from
+// the cells around the replanted one, as only the cell's own region and the
+// regions it joins change. When the neighbourhood cannot rule out the cell's
+// region splitting, or the connectivity lets regions join beyond their
+// orthogonal neighbours, the replant is tried on a copy of the garden instead.
+func (fc *FencingCalculator) FindCheapestReplants(strategy PricingStrategy, top int) ([]ReplantCandidate, error) {
+	used := make(map[rune]bool)
+	for _, plants := range fc.garden {
+		for _, plant := range plants {
+			used[plant] = true
+		}
+	}
+
+	// A rate table can make planting something that joins no neighbour
+	// cheaper than joining one, so every plant it or the garden mentions is
+	// tried in every cell. The new plant is one neither mentions, priced at
+	// the default rate.
+	everywhere := make([]rune, 0)
+	if rateTable, ok := strategy.(*RateTablePricing); ok {
+		for plant := range rateTable.Rates {
+			used[plant] = true
+		}
+		for plant := range used {
+			everywhere = append(everywhere, plant)
+		}
+		slices.Sort(everywhere)
+	}
+
+	newPlant := 'A'
+	for used[newPlant] {
+		newPlant++
+	}
+
+	plots := make([]*GardenPlot, fc.nextEnclosure+1)
+	for _, plot := range fc.gardenPlots {
+		plots[plot.Enclosure] = plot
+	}
+
+	local := true
+	for _, direction := range orthogonalDirections {
+		local = local && slices.Contains(fc.connectivity, direction)
+	}
+
+	var scratch *FencingCalculator
+	candidates := make([]ReplantCandidate, 0)
+	for row, plants := range fc.garden {
+		for column, plant := range plants {
+			options := []rune{newPlant}
+			for _, direction := range fc.connectivity {
+				neighbour := Point{Row: row + direction.Row, Col: column + direction.Col}
+				if neighbour.Row < 0 || neighbour.Row >= len(fc.garden) || neighbour.Col < 0 || neighbour.Col >= len(fc.garden[neighbour.Row]) {
+					continue
+				}
+
+				option := fc.garden[neighbour.Row][neighbour.Col]
+				if option != plant && !slices.Contains(options, option) {
+					options = append(options, option)
+				}
+			}
+			for _, option := range everywhere {
+				if option != plant && !slices.Contains(options, option) {
+					options = append(options, option)
+				}
+			}
+
+			scoreLocally := local && !fc.maySplit(row, column)
+			for _, option := range options {
+				var result ReplantResult
+				if scoreLocally {
+					result = fc.scoreReplant(row, column, option, plots)
+				} else {
+					if scratch == nil {
+						scratch = fc.Clone()
+					}
+
+					var err error
+					result, err = scratch.Replant(row, column, option)
+					if err != nil {
+						return nil, err
+					}
+
+					_, err = scratch.Replant(row, column, plant)
+					if err != nil {
+						return nil, err
+					}
+				}
+
+				candidates = append(candidates, ReplantCandidate{
+					Row:         row,
+					Column:      column,
+					From:        plant,
+					To:          option,
+					NewPlant:    option == newPlant,
+					PriceChange: result.PriceChange(strategy),
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].PriceChange < candidates[j].PriceChange
+	})
+
+	return candidates[:min(top, len(candidates))], nil
+}
+
+// maySplit checks whether the cell's region could fall apart without the cell,
+// by walking from one of the region's cells it joins to the others without
+// leaving the cells around it. A walk that stays close may miss a way round
+// further off, so it can raise a false alarm but never misses a split.
+func (fc *FencingCalculator) maySplit(row int, column int) bool {
+	enclosure := fc.enclosures[row][column]
+
+	reach := 1
+	for _, direction := range fc.connectivity {
+		reach = max(reach, direction.Row, -direction.Row, direction.Col, -direction.Col)
+	}
+	width := 2*reach + 1
+
+	nearby := func(point Point) bool {
+		return point.Row >= row-reach && point.Row <= row+reach && point.Col >= column-reach && point.Col <= column+reach &&
+			point != Point{Row: row, Col: column} && fc.InEnclosure(point.Row, point.Col, enclosure)
+	}
+	index := func(point Point) int {
+		return (point.Row-row+reach)*width + point.Col - column + reach
+	}
+
+	joined := make([]Point, 0, len(fc.connectivity))
+	for _, direction := range fc.connectivity {
+		neighbour := Point{Row: row + direction.Row, Col: column + direction.Col}
+		if fc.InEnclosure(neighbour.Row, neighbour.Col, enclosure) {
+			joined = append(joined, neighbour)
+		}
+	}
+	if len(joined) <= 1 {
+		return false
+	}
+
+	visited := make([]bool, width*width)
+	visited[index(joined[0])] = true
+	walk := []Point{joined[0]}
+	for i := 0; i < len(walk); i++ {
+		for _, direction := range fc.connectivity {
+			neighbour := Point{Row: walk[i].Row + direction.Row, Col: walk[i].Col + direction.Col}
+			if nearby(neighbour) && !visited[index(neighbour)] {
+				visited[index(neighbour)] = true
+				walk = append(walk, neighbour)
+			}
+		}
+	}
+
+	for _, neighbour := range joined[1:] {
+		if !visited[index(neighbour)] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// scoreReplant works out which regions replanting the cell would remove and
+// add without touching the garden. It relies on the cell's region staying in
+// one piece and on regions only joining orthogonal neighbours, so that every
+// fence and corner that moves is next to the cell.
+func (fc *FencingCalculator) scoreReplant(row int, column int, plant rune, plots []*GardenPlot) ReplantResult {
+	isReplanted := func(r int, c int) bool {
+		return r == row && c == column
+	}
+
+	// cornersAround sums the corners of a region on the cell and the cells
+	// around it, the only corners the replant can change.
+	cornersAround := func(inRegion func(row int, column int) bool) int {
+		corners := 0
+		for r := row - 1; r <= row+1; r++ {
+			for c := column - 1; c <= column+1; c++ {
+				if inRegion(r, c) {
+					corners += CountCorners(r, c, inRegion)
+				}
+			}
+		}
+
+		return corners
+	}
+
+	result := ReplantResult{}
+
+	enclosure := fc.enclosures[row][column]
+	oldPlot := plots[enclosure]
+	result.Removed = append(result.Removed, oldPlot)
+
+	if oldPlot.area > 1 {
+		inOldRegion := func(r int, c int) bool {
+			return fc.InEnclosure(r, c, enclosure)
+		}
+		inRemainingRegion := func(r int, c int) bool {
+			return !isReplanted(r, c) && fc.InEnclosure(r, c, enclosure)
+		}
+
+		remaining := &GardenPlot{
+			Plant:     oldPlot.Plant,
+			Enclosure: enclosure,
+			area:      oldPlot.area - 1,
+			perimiter: oldPlot.perimiter,
+			sides:     oldPlot.sides - cornersAround(inOldRegion) + cornersAround(inRemainingRegion),
+		}
+
+		// Every fence the cell had goes, and every neighbour it leaves behind
+		// gains one.
+		for _, direction := range orthogonalDirections {
+			if fc.InEnclosure(row+direction.Row, column+direction.Col, enclosure) {
+				remaining.perimiter++
+			} else {
+				remaining.perimiter--
+			}
+		}
+
+		result.Added = append(result.Added, remaining)
+	}
+
+	joined := make([]int, 0, len(fc.connectivity))
+	for _, direction := range fc.connectivity {
+		neighbour := Point{Row: row + direction.Row, Col: column + direction.Col}
+		if fc.HasPlant(neighbour.Row, neighbour.Col, plant) && !slices.Contains(joined, fc.enclosures[neighbour.Row][neighbour.Col]) {
+			joined = append(joined, fc.enclosures[neighbour.Row][neighbour.Col])
+		}
+	}
+
+	inMergedRegion := func(r int, c int) bool {
+		if r < 0 || r >= len(fc.enclosures) || c < 0 || c >= len(fc.enclosures[r]) {
+			return false
+		}
+
+		return isReplanted(r, c) || slices.Contains(joined, fc.enclosures[r][c])
+	}
+
+	merged := &GardenPlot{
+		Plant:     plant,
+		area:      1,
+		perimiter: 4,
+		sides:     cornersAround(inMergedRegion),
+	}
+	for _, joinedEnclosure := range joined {
+		plot := plots[joinedEnclosure]
+		result.Removed = append(result.Removed, plot)
+
+		merged.area += plot.area
+		merged.perimiter += plot.perimiter
+		merged.sides += plot.sides - cornersAround(func(r int, c int) bool {
+			return fc.InEnclosure(r, c, joinedEnclosure)
+		})
+	}
+	for _, direction := range orthogonalDirections {
+		if inMergedRegion(row+direction.Row, column+direction.Col) {
+			merged.perimiter -= 2
+		}
+	}
+	result.Added = append(result.Added, merged)
+
+	return result
+}
+
+func WriteReplantCandidates(w io.Writer, candidates []ReplantCandidate, strategy PricingStrategy) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	fmt.Fprintf(writer, "Cheapest replants by %s\n", strategy.Name())
+	fmt.Fprint(writer, "rank\trow\tcolumn\tfrom\tto\tprice\t\n")
+	for i, candidate := range candidates {
+		to := string(candidate.To)
+		if candidate.NewPlant {
+			to = "new"
+		}
+
+		fmt.Fprintf(writer, "%d\t%d\t%d\t%c\t%s\t%+.2f\t\n", i+1, candidate.Row, candidate.Column, candidate.From, to, candidate.PriceChange)
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// replantEveryCell prices replanting every cell with every one of the plants,
+// by replanting it on a copy of the garden.
+func replantEveryCell(t *testing.T, fc *FencingCalculator, strategy PricingStrategy, options []rune) map[Replanting]float64 {
+	t.Helper()
+
+	scratch := fc.Clone()
+	priceChanges := make(map[Replanting]float64)
+	for row, plants := range fc.garden {
+		for column, plant := range plants {
+			for _, option := range options {
+				if option == plant {
+					continue
+				}
+
+				result, err := scratch.Replant(row, column, option)
+				if err != nil {
+					t.Fatal(err)
+				}
+				priceChanges[Replanting{Row: row, Column: column, Plant: option}] = result.PriceChange(strategy)
+
+				_, err = scratch.Replant(row, column, plant)
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+	}
+
+	return priceChanges
+}
+
+// samePrice allows for prices being added up in another order.
+func samePrice(a float64, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func TestFindCheapestReplantsMatchesReplanting(t *testing.T) {
+	strategies := []PricingStrategy{
+		PerimeterPricing{},
+		SidesPricing{},
+		&RateTablePricing{Base: SidesPricing{}, Rates: map[rune]float64{'A': 1.5, 'B': 0.25, 'E': 0.1}, DefaultRate: 1},
+	}
+
+	random := rand.New(rand.NewSource(47))
+	for _, connectivity := range []string{"4", "8", "0,1;1,0;0,2", "0,2;1,1"} {
+		for i := 0; i < 60; i++ {
+			rows, columns := 1+random.Intn(8), 1+random.Intn(8)
+			lines := make([]string, rows)
+			for row := range lines {
+				plants := make([]byte, columns)
+				for column := range plants {
+					plants[column] = "ABC"[random.Intn(2+random.Intn(2))]
+				}
+				lines[row] = string(plants)
+			}
+
+			fencingCalculator := examineGarden(t, lines, connectivity)
+			for _, strategy := range strategies {
+				expected := replantEveryCell(t, fencingCalculator, strategy, []rune("ABCDEF"))
+
+				actual, err := fencingCalculator.FindCheapestReplants(strategy, len(expected)+1)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				newPlantPriceChanges := make(map[Point]float64)
+				found := make(map[Replanting]bool)
+				for j, candidate := range actual {
+					replanting := Replanting{Row: candidate.Row, Column: candidate.Column, Plant: candidate.To}
+					priceChange, ok := expected[replanting]
+					if !ok || !samePrice(priceChange, candidate.PriceChange) {
+						t.Fatalf("connectivity %s, %s, garden %q: candidate %+v should change the price by %v",
+							connectivity, strategy.Name(), lines, candidate, priceChange)
+					}
+					if j > 0 && actual[j-1].PriceChange > candidate.PriceChange {
+						t.Fatalf("connectivity %s, %s, garden %q: candidates are not sorted", connectivity, strategy.Name(), lines)
+					}
+
+					found[replanting] = true
+					if candidate.NewPlant {
+						newPlantPriceChanges[Point{Row: candidate.Row, Col: candidate.Column}] = candidate.PriceChange
+					}
+				}
+
+				// A plant left out has to join no neighbour and cost the same
+				// as the new plant. With a rate table that rules out every
+				// plant the table or the garden mentions.
+				mentioned := func(plant rune) bool {
+					rateTable, ok := strategy.(*RateTablePricing)
+					if !ok {
+						return false
+					}
+
+					_, rated := rateTable.Rates[plant]
+					return rated || strings.ContainsRune(strings.Join(lines, ""), plant)
+				}
+				for replanting, priceChange := range expected {
+					if found[replanting] {
+						continue
+					}
+
+					newPlantPriceChange, ok := newPlantPriceChanges[Point{Row: replanting.Row, Col: replanting.Column}]
+					if mentioned(replanting.Plant) || !ok || !samePrice(priceChange, newPlantPriceChange) {
+						t.Fatalf("connectivity %s, %s, garden %q: replanting %+v changes the price by %v but was not tried",
+							connectivity, strategy.Name(), lines, replanting, priceChange)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFindCheapestReplantsWithRateTable(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{"ABAC"}, "4")
+	strategy := &RateTablePricing{Base: PerimeterPricing{}, Rates: map[rune]float64{'C': 0.1}, DefaultRate: 1}
+
+	candidates, err := fencingCalculator.FindCheapestReplants(strategy, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Fencing a lone C costs a tenth as much, so turning the B into a C
+	// saves more than turning it into either A or a new plant.
+	replantB := func(candidate ReplantCandidate) bool { return candidate.Column == 1 }
+	best := candidates[slices.IndexFunc(candidates, replantB)]
+	if best.To != 'C' || !samePrice(best.PriceChange, -3.6) {
+		t.Errorf("expected replanting the B with a C to save 3.6, got %+v", best)
+	}
+	if !samePrice(candidates[0].PriceChange, -3.6) {
+		t.Errorf("expected the cheapest replant to save 3.6, got %+v", candidates[0])
+	}
+}
+
+func TestFindCheapestReplantsLeavesGardenAlone(t *testing.T) {
+	lines := []string{"AAAA", "BBCD", "BBCC", "EEEC"}
+	fencingCalculator := examineGarden(t, lines, "4")
+
+	_, err := fencingCalculator.FindCheapestReplants(PerimeterPricing{}, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for row, line := range lines {
+		if string(fencingCalculator.garden[row]) != line {
+			t.Errorf("row %d changed from %q to %q", row, line, string(fencingCalculator.garden[row]))
+		}
+	}
+	if price := fencingCalculator.CalculateTotalFencingPrice(); price != 140 {
+		t.Errorf("expected the fencing price to stay 140, got %d", price)
+	}
+}
+
+func BenchmarkFindCheapestReplants(b *testing.B) {
+	lines := make([]string, 100)
+	for row := range lines {
+		lines[row] = strings.Repeat("A", 100)
+	}
+	fencingCalculator := examineGarden(b, lines, "4")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := fencingCalculator.FindCheapestReplants(PerimeterPricing{}, 10)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
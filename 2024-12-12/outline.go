@@ -0,0 +1,367 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// A Ring is a closed loop of fence corners in grid coordinates, where the
+// corner at (row, column) is the top left corner of the cell at (row, column).
+// The last corner joins back up with the first.
+type Ring []Point
+
+type Polygon struct {
+	Outer Ring
+	Holes []Ring
+}
+
+type Outline struct {
+	Plot     *GardenPlot
+	Polygons []Polygon
+}
+
+type fenceEdge struct {
+	start     Point
+	direction int
+}
+
+func turnRight(direction int) int {
+	return (direction + 1) % 4
+}
+
+func turnLeft(direction int) int {
+	return (direction + 3) % 4
+}
+
+// TraceOutline follows the plot's fences, with the plot on the right, into
+// closed rings. Outer rings run clockwise on screen and holes run
+// anticlockwise, and each hole is given to the smallest outer ring around it.
+func (fc *FencingCalculator) TraceOutline(plot *GardenPlot) Outline {
+	edges := make(map[fenceEdge]bool)
+	for row := plot.bounds.Top; row <= plot.bounds.Bottom; row++ {
+		for column := plot.bounds.Left; column <= plot.bounds.Right; column++ {
+			if !fc.InEnclosure(row, column, plot.Enclosure) {
+				continue
+			}
+
+			// The corners the edge on each side of the cell starts from, in
+			// the same order as orthogonalDirections: up, right, down, left.
+			starts := []Point{
+				{Row: row, Col: column},
+				{Row: row, Col: column + 1},
+				{Row: row + 1, Col: column + 1},
+				{Row: row + 1, Col: column},
+			}
+			for side, direction := range orthogonalDirections {
+				if !fc.InEnclosure(row+direction.Row, column+direction.Col, plot.Enclosure) {
+					// A fence on the upper side runs right, the right side
+					// runs down, and so on round the cell.
+					edges[fenceEdge{start: starts[side], direction: turnRight(side)}] = true
+				}
+			}
+		}
+	}
+
+	rings := make([]Ring, 0)
+	for len(edges) > 0 {
+		var first fenceEdge
+		found := false
+		for edge := range edges {
+			if !found || edge.start.Row < first.start.Row ||
+				(edge.start.Row == first.start.Row && edge.start.Col < first.start.Col) ||
+				(edge.start == first.start && edge.direction < first.direction) {
+				first = edge
+				found = true
+			}
+		}
+
+		walk := []Point{first.start}
+		edge := first
+		for {
+			delete(edges, edge)
+
+			end := Point{
+				Row: edge.start.Row + orthogonalDirections[edge.direction].Row,
+				Col: edge.start.Col + orthogonalDirections[edge.direction].Col,
+			}
+			walk = append(walk, end)
+
+			if end == first.start {
+				break
+			}
+
+			for _, direction := range []int{turnRight(edge.direction), edge.direction, turnLeft(edge.direction)} {
+				candidate := fenceEdge{start: end, direction: direction}
+				if edges[candidate] {
+					edge = candidate
+					break
+				}
+			}
+		}
+
+		rings = append(rings, SplitWalk(walk)...)
+	}
+
+	polygons := make([]Polygon, 0)
+	holes := make([]Ring, 0)
+	for _, ring := range rings {
+		if ring.SignedArea() > 0 {
+			polygons = append(polygons, Polygon{Outer: ring})
+		} else {
+			holes = append(holes, ring)
+		}
+	}
+
+	for _, hole := range holes {
+		// The cell to the left of a hole's fence lies inside the hole, and
+		// its centre is never on a fence.
+		direction := hole.Direction(0)
+		centreRow := float64(hole[0].Row) + float64(direction.Row)/2 - float64(direction.Col)/2
+		centreColumn := float64(hole[0].Col) + float64(direction.Col)/2 + float64(direction.Row)/2
+
+		owner := -1
+		for i, polygon := range polygons {
+			if polygon.Outer.Contains(centreRow, centreColumn) &&
+				(owner < 0 || polygon.Outer.SignedArea() < polygons[owner].Outer.SignedArea()) {
+				owner = i
+			}
+		}
+
+		if owner >= 0 {
+			polygons[owner].Holes = append(polygons[owner].Holes, hole)
+		}
+	}
+
+	return Outline{Plot: plot, Polygons: polygons}
+}
+
+// SplitWalk breaks a closed walk along the fences into simple rings. Where a
+// region touches itself or another of its holes diagonally, the walk passes
+// through the same corner twice, and each loop between the two visits becomes
+// a ring of its own. Collinear fence segments are merged along the way so that
+// every corner of a ring is a real corner.
+func SplitWalk(walk []Point) []Ring {
+	rings := make([]Ring, 0)
+	path := make([]Point, 0, len(walk))
+	visited := make(map[Point]int)
+
+	for _, corner := range walk {
+		if i, ok := visited[corner]; ok {
+			rings = append(rings, MergeCollinear(path[i:]))
+			for _, looped := range path[i+1:] {
+				delete(visited, looped)
+			}
+			path = path[:i+1]
+			continue
+		}
+
+		visited[corner] = len(path)
+		path = append(path, corner)
+	}
+
+	return rings
+}
+
+func MergeCollinear(loop []Point) Ring {
+	ring := make(Ring, 0)
+	for i, corner := range loop {
+		previous := loop[(i+len(loop)-1)%len(loop)]
+		next := loop[(i+1)%len(loop)]
+		if (corner.Row-previous.Row != next.Row-corner.Row) || (corner.Col-previous.Col != next.Col-corner.Col) {
+			ring = append(ring, corner)
+		}
+	}
+
+	// Start every ring from its top left corner so the output is stable.
+	start := 0
+	for i, corner := range ring {
+		if corner.Row < ring[start].Row || (corner.Row == ring[start].Row && corner.Col < ring[start].Col) {
+			start = i
+		}
+	}
+
+	return append(ring[start:], ring[:start]...)
+}
+
+// Direction returns the unit step from the ring's corner at index i towards
+// the next corner.
+func (r Ring) Direction(i int) Point {
+	next := r[(i+1)%len(r)]
+	return Point{Row: sign(next.Row - r[i].Row), Col: sign(next.Col - r[i].Col)}
+}
+
+func sign(value int) int {
+	if value < 0 {
+		return -1
+	} else if value > 0 {
+		return 1
+	}
+	return 0
+}
+
+// SignedArea is positive for rings running clockwise on screen.
+func (r Ring) SignedArea() int {
+	area := 0
+	for i, corner := range r {
+		next := r[(i+1)%len(r)]
+		area += corner.Col*next.Row - next.Col*corner.Row
+	}
+
+	return area / 2
+}
+
+func (r Ring) Contains(row float64, column float64) bool {
+	inside := false
+	for i, corner := range r {
+		next := r[(i+1)%len(r)]
+		if (float64(corner.Row) > row) != (float64(next.Row) > row) {
+			crossing := float64(corner.Col) + (row-float64(corner.Row))/float64(next.Row-corner.Row)*float64(next.Col-corner.Col)
+			if column < crossing {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+func (o Outline) Sides() int {
+	sides := 0
+	for _, polygon := range o.Polygons {
+		sides += len(polygon.Outer)
+		for _, hole := range polygon.Holes {
+			sides += len(hole)
+		}
+	}
+
+	return sides
+}
+
+func (fc *FencingCalculator) Outlines() []Outline {
+	outlines := make([]Outline, 0, len(fc.gardenPlots))
+	for _, plot := range fc.gardenPlots {
+		outlines = append(outlines, fc.TraceOutline(plot))
+	}
+
+	return outlines
+}
+
+func (fc *FencingCalculator) WriteOutlines(w io.Writer, format string) error {
+	switch format {
+	case "geojson":
+		return fc.WriteGeoJson(w)
+	case "wkt":
+		return fc.WriteWkt(w)
+	default:
+		return fmt.Errorf("unknown outline format %q, expected geojson or wkt", format)
+	}
+}
+
+// Exported outlines put x along the columns and y up the rows from the bottom
+// of the garden, so north stays at the top in GIS tools. The rings are walked
+// backwards so outer rings run anticlockwise and holes clockwise, as GeoJSON
+// asks.
+func (fc *FencingCalculator) exportRing(ring Ring) [][2]int {
+	coordinates := make([][2]int, 0, len(ring)+1)
+	for i := len(ring); i >= 0; i-- {
+		corner := ring[i%len(ring)]
+		coordinates = append(coordinates, [2]int{corner.Col, len(fc.garden) - corner.Row})
+	}
+
+	return coordinates
+}
+
+func (fc *FencingCalculator) exportPolygon(polygon Polygon) [][][2]int {
+	rings := [][][2]int{fc.exportRing(polygon.Outer)}
+	for _, hole := range polygon.Holes {
+		rings = append(rings, fc.exportRing(hole))
+	}
+
+	return rings
+}
+
+func (fc *FencingCalculator) WriteGeoJson(w io.Writer) error {
+	type geometry struct {
+		Type        string `json:"type"`
+		Coordinates any    `json:"coordinates"`
+	}
+
+	type feature struct {
+		Type       string         `json:"type"`
+		Geometry   geometry       `json:"geometry"`
+		Properties map[string]any `json:"properties"`
+	}
+
+	features := make([]feature, 0, len(fc.gardenPlots))
+	for _, outline := range fc.Outlines() {
+		var geom geometry
+		if len(outline.Polygons) == 1 {
+			geom = geometry{Type: "Polygon", Coordinates: fc.exportPolygon(outline.Polygons[0])}
+		} else {
+			polygons := make([][][][2]int, 0, len(outline.Polygons))
+			for _, polygon := range outline.Polygons {
+				polygons = append(polygons, fc.exportPolygon(polygon))
+			}
+			geom = geometry{Type: "MultiPolygon", Coordinates: polygons}
+		}
+
+		report := outline.Plot.Report()
+		features = append(features, feature{
+			Type:     "Feature",
+			Geometry: geom,
+			Properties: map[string]any{
+				"enclosure": report.Enclosure,
+				"plant":     report.Plant,
+				"area":      report.Area,
+				"perimeter": report.Perimeter,
+				"sides":     report.Sides,
+				"price":     report.Price,
+			},
+		})
+	}
+
+	encoder := json.NewEncoder(w)
+	return encoder.Encode(map[string]any{
+		"type":     "FeatureCollection",
+		"features": features,
+	})
+}
+
+func (fc *FencingCalculator) WriteWkt(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"enclosure", "plant", "wkt"}); err != nil {
+		return err
+	}
+
+	for _, outline := range fc.Outlines() {
+		polygons := make([]string, 0, len(outline.Polygons))
+		for _, polygon := range outline.Polygons {
+			rings := make([]string, 0)
+			for _, ring := range fc.exportPolygon(polygon) {
+				corners := make([]string, 0, len(ring))
+				for _, corner := range ring {
+					corners = append(corners, fmt.Sprintf("%d %d", corner[0], corner[1]))
+				}
+				rings = append(rings, "("+strings.Join(corners, ", ")+")")
+			}
+			polygons = append(polygons, "("+strings.Join(rings, ", ")+")")
+		}
+
+		wkt := "POLYGON " + polygons[0]
+		if len(polygons) > 1 {
+			wkt = "MULTIPOLYGON (" + strings.Join(polygons, ", ") + ")"
+		}
+
+		record := []string{strconv.Itoa(outline.Plot.Enclosure), string(outline.Plot.Plant), wkt}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"sync"
+)
+
+// ExamineGardenParallel splits the garden into horizontal strips and labels
+// each strip on its own goroutine. Regions crossing from one strip into an
+// earlier one are then joined with a disjoint-set, looking as far back as the
+// connectivity reaches, and renumbered in the order their first cell appears
+// so the enclosures and plots are exactly the ones ExamineGarden finds.
+func (fc *FencingCalculator) ExamineGardenParallel(workers int) {
+	numRows := len(fc.garden)
+	if workers <= 1 || numRows < workers {
+		fc.ExamineGarden()
+		return
+	}
+
+	stripHeight := (numRows + workers - 1) / workers
+	tops := make([]int, 0, workers+1)
+	for top := 0; top < numRows; top += stripHeight {
+		tops = append(tops, top)
+	}
+	numStrips := len(tops)
+	tops = append(tops, numRows)
+
+	numLabels := make([]int, numStrips)
+	forEachStrip(numStrips, func(strip int) {
+		numLabels[strip] = fc.labelStrip(tops[strip], tops[strip+1])
+	})
+
+	// Every strip numbered its labels from one, so each is offset into a single
+	// disjoint-set covering the whole garden.
+	offsets := make([]int, numStrips+1)
+	for strip := range numLabels {
+		offsets[strip+1] = offsets[strip] + numLabels[strip]
+	}
+
+	disjointSet := NewDisjointSet(offsets[numStrips])
+	label := func(row int, column int) int {
+		return offsets[row/stripHeight] + fc.enclosures[row][column] - 1
+	}
+
+	reach := 0
+	for _, direction := range fc.connectivity {
+		reach = max(reach, direction.Row, -direction.Row)
+	}
+
+	for strip := 1; strip < numStrips; strip++ {
+		top := tops[strip]
+		for row := top; row < min(top+reach, tops[strip+1]); row++ {
+			for column, plant := range fc.garden[row] {
+				for _, direction := range fc.connectivity {
+					neighbour := Point{Row: row + direction.Row, Col: column + direction.Col}
+					if neighbour.Row >= top || !fc.HasPlant(neighbour.Row, neighbour.Col, plant) {
+						continue
+					}
+
+					disjointSet.Union(label(row, column), label(neighbour.Row, neighbour.Col))
+				}
+			}
+		}
+	}
+
+	// Strips are in order and each numbered its labels in raster order, so
+	// walking the labels in order meets every region at its first cell.
+	numEnclosures := 0
+	enclosureIds := make([]int, disjointSet.Len())
+	for i := range enclosureIds {
+		root := disjointSet.Find(i)
+		if enclosureIds[root] == 0 {
+			numEnclosures++
+			enclosureIds[root] = numEnclosures
+		}
+		enclosureIds[i] = enclosureIds[root]
+	}
+
+	enclosure := func(row int, column int) int {
+		return enclosureIds[label(row, column)]
+	}
+
+	// Each strip measures its share of every region against the final ids
+	// before any strip is relabelled, keeping its partial plots by local label.
+	partials := make([][]*GardenPlot, numStrips)
+	forEachStrip(numStrips, func(strip int) {
+		partials[strip] = fc.measureStrip(tops[strip], tops[strip+1], numLabels[strip], enclosure)
+	})
+
+	fc.gardenPlots = make([]*GardenPlot, numEnclosures)
+	for i := range fc.gardenPlots {
+		fc.gardenPlots[i] = &GardenPlot{Enclosure: i + 1}
+	}
+	for strip, partial := range partials {
+		for i, plot := range partial {
+			gardenPlot := fc.gardenPlots[enclosureIds[offsets[strip]+i]-1]
+			gardenPlot.Plant = plot.Plant
+			gardenPlot.Merge(plot)
+		}
+	}
+
+	forEachStrip(numStrips, func(strip int) {
+		for row := tops[strip]; row < tops[strip+1]; row++ {
+			for column := range fc.enclosures[row] {
+				fc.enclosures[row][column] = enclosureIds[offsets[strip]+fc.enclosures[row][column]-1]
+			}
+		}
+	})
+
+	fc.nextEnclosure = numEnclosures
+	fc.containmentStale = true
+}
+
+func forEachStrip(numStrips int, work func(strip int)) {
+	var waitGroup sync.WaitGroup
+	for strip := 0; strip < numStrips; strip++ {
+		waitGroup.Add(1)
+		go func(strip int) {
+			defer waitGroup.Done()
+			work(strip)
+		}(strip)
+	}
+	waitGroup.Wait()
+}
+
+// labelStrip floods the regions of the rows from top up to bottom without
+// leaving the strip, numbering them from one in raster order, and returns how
+// many it found.
+func (fc *FencingCalculator) labelStrip(top int, bottom int) int {
+	numColumns := len(fc.garden[0])
+	numLabels := 0
+
+	inStrip := func(point Point, plant rune) bool {
+		return point.Row >= top && point.Row < bottom && fc.HasPlant(point.Row, point.Col, plant)
+	}
+
+	region := make([]Point, 0)
+	for row := top; row < bottom; row++ {
+		for column := 0; column < numColumns; column++ {
+			if fc.enclosures[row][column] != 0 {
+				continue
+			}
+
+			numLabels++
+			plant := fc.garden[row][column]
+
+			fc.enclosures[row][column] = numLabels
+			region = append(region[:0], Point{Row: row, Col: column})
+			for i := 0; i < len(region); i++ {
+				point := region[i]
+				for _, direction := range fc.connectivity {
+					neighbour := Point{Row: point.Row + direction.Row, Col: point.Col + direction.Col}
+					if inStrip(neighbour, plant) && fc.enclosures[neighbour.Row][neighbour.Col] == 0 {
+						fc.enclosures[neighbour.Row][neighbour.Col] = numLabels
+						region = append(region, neighbour)
+					}
+				}
+			}
+		}
+	}
+
+	return numLabels
+}
+
+// measureStrip adds up the area, fences and corners of the cells in the rows
+// from top up to bottom for each of the strip's labels, looking up which
+// region any cell is in with enclosure.
+func (fc *FencingCalculator) measureStrip(top int, bottom int, numLabels int, enclosure func(row int, column int) int) []*GardenPlot {
+	plots := make([]*GardenPlot, numLabels)
+	for row := top; row < bottom; row++ {
+		for column, label := range fc.enclosures[row] {
+			plot := plots[label-1]
+			if plot == nil {
+				plot = &GardenPlot{Plant: fc.garden[row][column]}
+				plots[label-1] = plot
+			}
+
+			plot.AddCell(Point{Row: row, Col: column})
+
+			cellEnclosure := enclosure(row, column)
+			inRegion := func(row int, column int) bool {
+				return row >= 0 && row < len(fc.enclosures) && column >= 0 && column < len(fc.enclosures[row]) &&
+					enclosure(row, column) == cellEnclosure
+			}
+
+			for _, direction := range orthogonalDirections {
+				if !inRegion(row+direction.Row, column+direction.Col) {
+					plot.perimiter += 1
+				}
+			}
+
+			plot.sides += CountCorners(row, column, inRegion)
+		}
+	}
+
+	return plots
+}
+
+type DisjointSet struct {
+	parents []int
+	ranks   []uint8
+}
+
+func NewDisjointSet(size int) *DisjointSet {
+	parents := make([]int, size)
+	for i := range parents {
+		parents[i] = i
+	}
+
+	return &DisjointSet{
+		parents: parents,
+		ranks:   make([]uint8, size),
+	}
+}
+
+func (ds *DisjointSet) Len() int {
+	return len(ds.parents)
+}
+
+func (ds *DisjointSet) Find(i int) int {
+	for ds.parents[i] != i {
+		ds.parents[i] = ds.parents[ds.parents[i]]
+		i = ds.parents[i]
+	}
+
+	return i
+}
+
+func (ds *DisjointSet) Union(a int, b int) {
+	a, b = ds.Find(a), ds.Find(b)
+	if a == b {
+		return
+	}
+
+	if ds.ranks[a] < ds.ranks[b] {
+		a, b = b, a
+	}
+	ds.parents[b] = a
+	if ds.ranks[a] == ds.ranks[b] {
+		ds.ranks[a]++
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"math/rand"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestExamineGardenParallelMatchesExamineGarden(t *testing.T) {
+	// The long stencils reach further than a strip is high once there are
+	// more than a few workers, so regions join strips that are not next to
+	// each other.
+	connectivities := []string{"4", "8", "3,1;0,2", "5,0;-1,1", "9,0;0,1"}
+
+	random := rand.New(rand.NewSource(45))
+	for _, connectivity := range connectivities {
+		for i := 0; i < 60; i++ {
+			rows, columns := 1+random.Intn(24), 1+random.Intn(10)
+			plants := 1 + i%4
+			lines := make([]string, rows)
+			for row := range lines {
+				line := make([]byte, columns)
+				for column := range line {
+					line[column] = byte('A' + random.Intn(plants))
+				}
+				lines[row] = string(line)
+			}
+
+			expected := examineGarden(t, lines, connectivity)
+			expected.ContainmentForest()
+
+			for workers := 1; workers <= rows+1; workers++ {
+				actual, err := NewFencingCalculator(lines)
+				if err != nil {
+					t.Fatal(err)
+				}
+				stencil, _ := ParseConnectivity(connectivity)
+				actual.SetConnectivity(stencil)
+				actual.ExamineGardenParallel(workers)
+				actual.ContainmentForest()
+
+				for row := range expected.enclosures {
+					if !slices.Equal(expected.enclosures[row], actual.enclosures[row]) {
+						t.Fatalf("connectivity %s, %d workers, garden %q: row %d is labelled %v, expected %v",
+							connectivity, workers, lines, row, actual.enclosures[row], expected.enclosures[row])
+					}
+				}
+				if actual.nextEnclosure != expected.nextEnclosure || len(actual.gardenPlots) != len(expected.gardenPlots) {
+					t.Fatalf("connectivity %s, %d workers, garden %q: found %d regions, expected %d",
+						connectivity, workers, lines, len(actual.gardenPlots), len(expected.gardenPlots))
+				}
+
+				for j, plot := range actual.gardenPlots {
+					if !samePlot(plot, expected.gardenPlots[j]) {
+						t.Fatalf("connectivity %s, %d workers, garden %q: region %+v, expected %+v",
+							connectivity, workers, lines, *plot, *expected.gardenPlots[j])
+					}
+				}
+			}
+		}
+	}
+}
+
+// samePlot compares two plots, and their parents by enclosure id as the two
+// calculators each have their own plots.
+func samePlot(a *GardenPlot, b *GardenPlot) bool {
+	if (a.parent == nil) != (b.parent == nil) || (a.parent != nil && a.parent.Enclosure != b.parent.Enclosure) ||
+		len(a.children) != len(b.children) {
+		return false
+	}
+
+	plotA, plotB := *a, *b
+	plotA.parent, plotA.children = nil, nil
+	plotB.parent, plotB.children = nil, nil
+	return reflect.DeepEqual(plotA, plotB)
+}
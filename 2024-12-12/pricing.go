@@ -0,0 +1,224 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"unicode/utf8"
+)
+
+type PricingStrategy interface {
+	Name() string
+	Price(plot *GardenPlot) float64
+}
+
+type PerimeterPricing struct{}
+
+func (PerimeterPricing) Name() string {
+	return "perimeter"
+}
+
+func (PerimeterPricing) Price(plot *GardenPlot) float64 {
+	return float64(plot.CalculateFencingPrice())
+}
+
+type SidesPricing struct{}
+
+func (SidesPricing) Name() string {
+	return "sides"
+}
+
+func (SidesPricing) Price(plot *GardenPlot) float64 {
+	return float64(plot.CalculateBulkFencingPrice())
+}
+
+// RateTablePricing scales another strategy's price by a per-plant multiplier,
+// for when each crop is fenced with a different material. Plants missing from
+// the table use the default rate.
+type RateTablePricing struct {
+	Base        PricingStrategy
+	Rates       map[rune]float64
+	DefaultRate float64
+}
+
+func (rtp *RateTablePricing) Name() string {
+	return rtp.Base.Name() + " with rate table"
+}
+
+func (rtp *RateTablePricing) Rate(plant rune) float64 {
+	rate, ok := rtp.Rates[plant]
+	if !ok {
+		return rtp.DefaultRate
+	}
+
+	return rate
+}
+
+func (rtp *RateTablePricing) Price(plot *GardenPlot) float64 {
+	return rtp.Base.Price(plot) * rtp.Rate(plot.Plant)
+}
+
+func ParsePricingStrategy(name string) (PricingStrategy, error) {
+	switch name {
+	case "perimeter":
+		return PerimeterPricing{}, nil
+	case "sides":
+		return SidesPricing{}, nil
+	default:
+		return nil, fmt.Errorf("unknown pricing strategy %q, expected perimeter or sides", name)
+	}
+}
+
+// LoadRateTable reads a JSON object mapping each plant to its cost multiplier,
+// such as {"A": 1.5, "B": 0.8, "*": 1}, where "*" sets the default rate.
+func LoadRateTable(path string, base PricingStrategy) (*RateTablePricing, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var table map[string]float64
+	err = json.Unmarshal(contents, &table)
+	if err != nil {
+		return nil, fmt.Errorf("reading rate table %s: %w", path, err)
+	}
+
+	pricing := &RateTablePricing{
+		Base:        base,
+		Rates:       make(map[rune]float64),
+		DefaultRate: 1,
+	}
+
+	for plant, rate := range table {
+		if rate < 0 {
+			return nil, fmt.Errorf("reading rate table %s: rate %v for %q is negative", path, rate, plant)
+		}
+
+		if plant == "*" {
+			pricing.DefaultRate = rate
+			continue
+		}
+
+		if utf8.RuneCountInString(plant) != 1 {
+			return nil, fmt.Errorf("reading rate table %s: %q is not a single plant", path, plant)
+		}
+
+		r, _ := utf8.DecodeRuneInString(plant)
+		err = ValidatePlant(r)
+		if err != nil {
+			return nil, fmt.Errorf("reading rate table %s: %w", path, err)
+		}
+
+		pricing.Rates[r] = rate
+	}
+
+	return pricing, nil
+}
+
+// LoadPricingStrategy picks the strategy by name and scales it by the rate
+// table at the path, if there is one.
+func LoadPricingStrategy(name string, ratesPath string) (PricingStrategy, error) {
+	strategy, err := ParsePricingStrategy(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if ratesPath == "" {
+		return strategy, nil
+	}
+
+	return LoadRateTable(ratesPath, strategy)
+}
+
+func (fc *FencingCalculator) CalculateTotalPrice(strategy PricingStrategy) float64 {
+	totalPrice := 0.0
+	for _, plot := range fc.gardenPlots {
+		totalPrice += strategy.Price(plot)
+	}
+
+	return totalPrice
+}
+
+type PlantBudget struct {
+	Plant   rune
+	Regions int
+	Area    int
+	Price   float64
+}
+
+func (fc *FencingCalculator) PlantBudgets(strategy PricingStrategy) []PlantBudget {
+	budgets := make(map[rune]*PlantBudget)
+	for _, plot := range fc.gardenPlots {
+		budget, ok := budgets[plot.Plant]
+		if !ok {
+			budget = &PlantBudget{Plant: plot.Plant}
+			budgets[plot.Plant] = budget
+		}
+
+		budget.Regions += 1
+		budget.Area += plot.area
+		budget.Price += strategy.Price(plot)
+	}
+
+	plantBudgets := make([]PlantBudget, 0, len(budgets))
+	for _, budget := range budgets {
+		plantBudgets = append(plantBudgets, *budget)
+	}
+
+	sort.Slice(plantBudgets, func(i, j int) bool {
+		if plantBudgets[i].Price != plantBudgets[j].Price {
+			return plantBudgets[i].Price > plantBudgets[j].Price
+		}
+		return plantBudgets[i].Plant < plantBudgets[j].Plant
+	})
+
+	return plantBudgets
+}
+
+// WriteBudgetReport breaks the cost down by plant and compares the total with
+// the budget. A budget of zero or less leaves the comparison out.
+func (fc *FencingCalculator) WriteBudgetReport(w io.Writer, strategy PricingStrategy, budget float64) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	rates, hasRates := strategy.(*RateTablePricing)
+
+	fmt.Fprintf(writer, "Pricing by %s\n", strategy.Name())
+	fmt.Fprint(writer, "plant\tregions\tarea\t")
+	if hasRates {
+		fmt.Fprint(writer, "rate\t")
+	}
+	fmt.Fprint(writer, "price\tshare\t\n")
+
+	total := fc.CalculateTotalPrice(strategy)
+	for _, plantBudget := range fc.PlantBudgets(strategy) {
+		fmt.Fprintf(writer, "%c\t%d\t%d\t", plantBudget.Plant, plantBudget.Regions, plantBudget.Area)
+		if hasRates {
+			fmt.Fprintf(writer, "%.2f\t", rates.Rate(plantBudget.Plant))
+		}
+
+		share := 0.0
+		if total > 0 {
+			share = plantBudget.Price / total * 100
+		}
+		fmt.Fprintf(writer, "%.2f\t%.1f%%\t\n", plantBudget.Price, share)
+	}
+
+	fmt.Fprintf(writer, "Total\t\t\t")
+	if hasRates {
+		fmt.Fprint(writer, "\t")
+	}
+	fmt.Fprintf(writer, "%.2f\t\t\n", total)
+
+	if budget > 0 {
+		if total <= budget {
+			fmt.Fprintf(writer, "Within budget of %.2f by %.2f\n", budget, budget-total)
+		} else {
+			fmt.Fprintf(writer, "Over budget of %.2f by %.2f\n", budget, total-budget)
+		}
+	}
+
+	return writer.Flush()
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"os"
+	"strings"
+)
+
+var fenceColour = color.RGBA{R: 0x20, G: 0x20, B: 0x20, A: 0xff}
+var labelColour = color.RGBA{R: 0x00, G: 0x00, B: 0x00, A: 0xff}
+
+// RegionColour steps the hue round the colour wheel by the golden angle for
+// each enclosure, so neighbouring enclosure ids never end up with similar
+// colours.
+func RegionColour(enclosure int) color.RGBA {
+	hue := math.Mod(float64(enclosure)*0.618033988749895, 1) * 6
+	saturation, value := 0.45, 0.95
+
+	chroma := value * saturation
+	x := chroma * (1 - math.Abs(math.Mod(hue, 2)-1))
+	m := value - chroma
+
+	var r, g, b float64
+	switch int(hue) {
+	case 0:
+		r, g, b = chroma, x, 0
+	case 1:
+		r, g, b = x, chroma, 0
+	case 2:
+		r, g, b = 0, chroma, x
+	case 3:
+		r, g, b = 0, x, chroma
+	case 4:
+		r, g, b = x, 0, chroma
+	default:
+		r, g, b = chroma, 0, x
+	}
+
+	return color.RGBA{
+		R: uint8(math.Round((r + m) * 255)),
+		G: uint8(math.Round((g + m) * 255)),
+		B: uint8(math.Round((b + m) * 255)),
+		A: 0xff,
+	}
+}
+
+func (gp *GardenPlot) Label() string {
+	return fmt.Sprintf("#%d $%d", gp.Enclosure, gp.CalculateFencingPrice())
+}
+
+func (fc *FencingCalculator) RenderSvgFile(path string, cellSize int, labels bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = fc.WriteSvg(file, cellSize, labels)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+func (fc *FencingCalculator) RenderPngFile(path string, cellSize int, labels bool) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	err = png.Encode(file, fc.RenderImage(cellSize, labels))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// WriteSvg draws each region as one path built from its outline, so holes are
+// cut out by the even-odd fill rule and the path's stroke is the region's fence.
+// Text is escaped, as plants such as < and & would otherwise break the XML.
+func (fc *FencingCalculator) WriteSvg(w io.Writer, cellSize int, labels bool) error {
+	width, height := len(fc.garden[0])*cellSize, len(fc.garden)*cellSize
+
+	lines := []string{
+		fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d">`, width, height, width, height),
+	}
+
+	strokeWidth := max(1, cellSize/8)
+	for _, outline := range fc.Outlines() {
+		var path strings.Builder
+		for _, polygon := range outline.Polygons {
+			for _, ring := range append([]Ring{polygon.Outer}, polygon.Holes...) {
+				for i, corner := range ring {
+					command := "L"
+					if i == 0 {
+						command = "M"
+					}
+					fmt.Fprintf(&path, "%s%d %d ", command, corner.Col*cellSize, corner.Row*cellSize)
+				}
+				path.WriteString("Z ")
+			}
+		}
+
+		colour := RegionColour(outline.Plot.Enclosure)
+		lines = append(lines, fmt.Sprintf(
+			`  <path d="%s" fill="#%02x%02x%02x" fill-rule="evenodd" stroke="#%02x%02x%02x" stroke-width="%d"><title>%s</title></path>`,
+			strings.TrimSpace(path.String()), colour.R, colour.G, colour.B,
+			fenceColour.R, fenceColour.G, fenceColour.B, strokeWidth,
+			html.EscapeString(fmt.Sprintf("%s %q", outline.Plot.Label(), outline.Plot.Plant))))
+	}
+
+	if labels {
+		fontSize := max(6, cellSize/2)
+		for _, plot := range fc.gardenPlots {
+			centroidRow, centroidColumn := plot.Centroid()
+			lines = append(lines, fmt.Sprintf(
+				`  <text x="%.1f" y="%.1f" font-family="monospace" font-size="%d" text-anchor="middle" dominant-baseline="middle">%s</text>`,
+				(centroidColumn+0.5)*float64(cellSize), (centroidRow+0.5)*float64(cellSize), fontSize, html.EscapeString(plot.Label())))
+		}
+	}
+
+	lines = append(lines, "</svg>")
+
+	_, err := fmt.Fprintln(w, strings.Join(lines, "\n"))
+	return err
+}
+
+// RenderImage paints every cell in its region's colour and then draws a fence
+// along every cell edge that separates two regions or faces the garden's edge.
+func (fc *FencingCalculator) RenderImage(cellSize int, labels bool) *image.RGBA {
+	numRows, numColumns := len(fc.enclosures), len(fc.enclosures[0])
+	img := image.NewRGBA(image.Rect(0, 0, numColumns*cellSize+1, numRows*cellSize+1))
+
+	fill := func(x0 int, y0 int, x1 int, y1 int, colour color.RGBA) {
+		for y := max(y0, 0); y < min(y1, img.Rect.Max.Y); y++ {
+			for x := max(x0, 0); x < min(x1, img.Rect.Max.X); x++ {
+				img.SetRGBA(x, y, colour)
+			}
+		}
+	}
+
+	for row := 0; row < numRows; row++ {
+		for column := 0; column < numColumns; column++ {
+			x, y := column*cellSize, row*cellSize
+			fill(x, y, x+cellSize, y+cellSize, RegionColour(fc.enclosures[row][column]))
+		}
+	}
+
+	thickness := max(1, cellSize/8)
+	for row := 0; row < numRows; row++ {
+		for column := 0; column < numColumns; column++ {
+			enclosure := fc.enclosures[row][column]
+			x, y := column*cellSize, row*cellSize
+
+			if !fc.InEnclosure(row-1, column, enclosure) {
+				fill(x-thickness/2, y-thickness/2, x+cellSize+thickness/2+1, y+thickness/2+1, fenceColour)
+			}
+			if !fc.InEnclosure(row, column-1, enclosure) {
+				fill(x-thickness/2, y-thickness/2, x+thickness/2+1, y+cellSize+thickness/2+1, fenceColour)
+			}
+			if row == numRows-1 {
+				fill(x-thickness/2, y+cellSize-thickness/2, x+cellSize+thickness/2+1, y+cellSize+thickness/2+1, fenceColour)
+			}
+			if column == numColumns-1 {
+				fill(x+cellSize-thickness/2, y-thickness/2, x+cellSize+thickness/2+1, y+cellSize+thickness/2+1, fenceColour)
+			}
+		}
+	}
+
+	if labels {
+		scale := max(1, cellSize/8)
+		for _, plot := range fc.gardenPlots {
+			centroidRow, centroidColumn := plot.Centroid()
+			label := plot.Label()
+
+			width := (len(label)*(glyphWidth+1) - 1) * scale
+			x := int((centroidColumn+0.5)*float64(cellSize)) - width/2
+			y := int((centroidRow+0.5)*float64(cellSize)) - glyphHeight*scale/2
+
+			for _, character := range label {
+				glyph, ok := glyphs[character]
+				if ok {
+					for glyphRow, bits := range glyph {
+						for glyphColumn := 0; glyphColumn < glyphWidth; glyphColumn++ {
+							if bits&(1<<(glyphWidth-1-glyphColumn)) != 0 {
+								px, py := x+glyphColumn*scale, y+glyphRow*scale
+								fill(px, py, px+scale, py+scale, labelColour)
+							}
+						}
+					}
+				}
+				x += (glyphWidth + 1) * scale
+			}
+		}
+	}
+
+	return img
+}
+
+const glyphWidth = 3
+const glyphHeight = 5
+
+// The standard library has no font rendering, so labels on PNGs are drawn with
+// a tiny bitmap font covering just the characters a label uses. Each row of a
+// glyph is three bits, most significant bit on the left.
+var glyphs = map[rune][glyphHeight]uint8{
+	'0': {0b111, 0b101, 0b101, 0b101, 0b111},
+	'1': {0b010, 0b110, 0b010, 0b010, 0b111},
+	'2': {0b111, 0b001, 0b111, 0b100, 0b111},
+	'3': {0b111, 0b001, 0b111, 0b001, 0b111},
+	'4': {0b101, 0b101, 0b111, 0b001, 0b001},
+	'5': {0b111, 0b100, 0b111, 0b001, 0b111},
+	'6': {0b111, 0b100, 0b111, 0b101, 0b111},
+	'7': {0b111, 0b001, 0b010, 0b010, 0b010},
+	'8': {0b111, 0b101, 0b111, 0b101, 0b111},
+	'9': {0b111, 0b101, 0b111, 0b001, 0b111},
+	'#': {0b101, 0b111, 0b101, 0b111, 0b101},
+	'$': {0b011, 0b110, 0b111, 0b011, 0b110},
+	'-': {0b000, 0b000, 0b111, 0b000, 0b000},
+	'.': {0b000, 0b000, 0b000, 0b000, 0b010},
+}
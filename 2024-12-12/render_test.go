@@ -0,0 +1,48 @@
+package main
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestWriteSvgEscapesPlants(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{`<&"`, `'>A`}, "4")
+
+	var svg strings.Builder
+	err := fencingCalculator.WriteSvg(&svg, 10, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	titles := make([]string, 0)
+	inTitle := false
+	decoder := xml.NewDecoder(strings.NewReader(svg.String()))
+	for {
+		token, err := decoder.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("the SVG is not valid XML: %v\n%s", err, svg.String())
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			inTitle = token.Name.Local == "title"
+		case xml.EndElement:
+			inTitle = false
+		case xml.CharData:
+			if inTitle {
+				titles = append(titles, string(token))
+			}
+		}
+	}
+
+	expected := []string{`#1 $4 '<'`, `#2 $4 '&'`, `#3 $4 '"'`, `#4 $4 '\''`, `#5 $4 '>'`, `#6 $4 'A'`}
+	if strings.Join(titles, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("expected titles %q, got %q", expected, titles)
+	}
+}
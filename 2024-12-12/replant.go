@@ -0,0 +1,333 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type ReplantResult struct {
+	Removed []*GardenPlot
+	Added   []*GardenPlot
+}
+
+// PriceChange is how much the replant changed the total price under the given
+// strategy.
+func (rr ReplantResult) PriceChange(strategy PricingStrategy) float64 {
+	change := 0.0
+	for _, plot := range rr.Added {
+		change += strategy.Price(plot)
+	}
+	for _, plot := range rr.Removed {
+		change -= strategy.Price(plot)
+	}
+
+	return change
+}
+
+// Replant changes the plant in one cell and re-examines only what the change
+// can reach. The cell joins the largest region of its new plant next to it and
+// any smaller ones are relabelled into it, while its old region is only flooded
+// again when losing the cell might split it, with pieces split off getting new
+// ids. Every other region keeps its id and its cells. A cell's fences and
+// corners only depend on which of the cells around it share its region, so
+// only the cells around those relabelled are measured again.
+// The containment forest is rebuilt the next time the calculator is asked for
+// it.
+func (fc *FencingCalculator) Replant(row int, column int, plant rune) (ReplantResult, error) {
+	if row < 0 || row >= len(fc.garden) || column < 0 || column >= len(fc.garden[row]) {
+		return ReplantResult{}, fmt.Errorf("cell %d,%d is outside the %dx%d garden", row, column, len(fc.garden), len(fc.garden[0]))
+	}
+
+	oldPlant := fc.garden[row][column]
+	if oldPlant == plant {
+		return ReplantResult{}, nil
+	}
+
+	replanted := Point{Row: row, Col: column}
+	oldEnclosure := fc.enclosures[row][column]
+
+	// The cell joins every region of the new plant it neighbours, and the
+	// largest of them keeps its id.
+	joined := make([]*GardenPlot, 0, len(fc.connectivity))
+	seeds := make(map[int]Point)
+	for _, direction := range fc.connectivity {
+		neighbour := Point{Row: row + direction.Row, Col: column + direction.Col}
+		if !fc.HasPlant(neighbour.Row, neighbour.Col, plant) {
+			continue
+		}
+
+		enclosure := fc.enclosures[neighbour.Row][neighbour.Col]
+		if _, ok := seeds[enclosure]; !ok {
+			seeds[enclosure] = neighbour
+			joined = append(joined, fc.plotOf(enclosure))
+		}
+	}
+	sort.Slice(joined, func(i, j int) bool {
+		if joined[i].area != joined[j].area {
+			return joined[i].area > joined[j].area
+		}
+		return joined[i].Enclosure < joined[j].Enclosure
+	})
+
+	splits := fc.maySplit(row, column)
+
+	relabelled := []Point{replanted}
+	for _, plot := range joined[min(1, len(joined)):] {
+		relabelled = append(relabelled, fc.regionCells(seeds[plot.Enclosure])...)
+	}
+	if splits {
+		relabelled = append(relabelled, fc.regionCells(replanted)[1:]...)
+	}
+
+	dirty := make(map[Point]bool)
+	for _, point := range relabelled {
+		for r := point.Row - 1; r <= point.Row+1; r++ {
+			for c := point.Col - 1; c <= point.Col+1; c++ {
+				if r >= 0 && r < len(fc.garden) && c >= 0 && c < len(fc.garden[r]) {
+					dirty[Point{Row: r, Col: c}] = true
+				}
+			}
+		}
+	}
+
+	// Each region touched is measured again on a copy of its plot, so the
+	// result still holds the plots from before the replant.
+	result := ReplantResult{}
+	plots := make(map[int]*GardenPlot)
+	working := func(enclosure int, plant rune) *GardenPlot {
+		plot, ok := plots[enclosure]
+		if ok {
+			return plot
+		}
+
+		if existing := fc.plotOf(enclosure); existing != nil {
+			result.Removed = append(result.Removed, existing)
+			copied := *existing
+			copied.parent, copied.children, copied.holePerimiter = nil, nil, 0
+			plot = &copied
+		} else {
+			plot = &GardenPlot{Plant: plant, Enclosure: enclosure}
+		}
+		plots[enclosure] = plot
+		return plot
+	}
+
+	for point := range dirty {
+		fc.UnmeasureCell(working(fc.enclosures[point.Row][point.Col], fc.garden[point.Row][point.Col]), point.Row, point.Col)
+	}
+
+	fc.garden[row][column] = plant
+	fc.containmentStale = true
+
+	var enclosure int
+	if len(joined) > 0 {
+		enclosure = joined[0].Enclosure
+	} else {
+		fc.nextEnclosure++
+		enclosure = fc.nextEnclosure
+	}
+	for _, point := range relabelled {
+		fc.enclosures[point.Row][point.Col] = 0
+	}
+	for _, point := range relabelled {
+		if fc.garden[point.Row][point.Col] == plant {
+			fc.enclosures[point.Row][point.Col] = enclosure
+		}
+	}
+
+	// The first piece of a split keeps the old id.
+	pieces := 0
+	for _, point := range relabelled {
+		if fc.enclosures[point.Row][point.Col] != 0 {
+			continue
+		}
+
+		pieceEnclosure := oldEnclosure
+		if pieces > 0 {
+			fc.nextEnclosure++
+			pieceEnclosure = fc.nextEnclosure
+		}
+		fc.FloodFill(point.Row, point.Col, oldPlant, pieceEnclosure)
+		pieces++
+	}
+
+	for point := range dirty {
+		fc.MeasureCell(working(fc.enclosures[point.Row][point.Col], fc.garden[point.Row][point.Col]), point.Row, point.Col)
+	}
+
+	// Only the old region can lose a cell and keep others, which may leave
+	// its bounding box too big.
+	if oldPlot := plots[oldEnclosure]; !splits && oldPlot.area > 0 {
+		fc.shrinkBounds(oldPlot)
+	}
+
+	for _, plot := range plots {
+		if plot.area > 0 {
+			result.Added = append(result.Added, plot)
+		}
+	}
+	sort.Slice(result.Removed, func(i, j int) bool {
+		return result.Removed[i].Enclosure < result.Removed[j].Enclosure
+	})
+	sort.Slice(result.Added, func(i, j int) bool {
+		return result.Added[i].Enclosure < result.Added[j].Enclosure
+	})
+
+	// Reused ids take over their old plot's place and new ids are larger than
+	// any before them, so the plots stay ordered by enclosure id.
+	gardenPlots := make([]*GardenPlot, 0, len(fc.gardenPlots)+len(result.Added))
+	for _, plot := range fc.gardenPlots {
+		if replacement, ok := plots[plot.Enclosure]; !ok {
+			gardenPlots = append(gardenPlots, plot)
+		} else if replacement.area > 0 {
+			gardenPlots = append(gardenPlots, replacement)
+		}
+	}
+	for _, plot := range result.Added {
+		if fc.plotOf(plot.Enclosure) == nil {
+			gardenPlots = append(gardenPlots, plot)
+		}
+	}
+	fc.gardenPlots = gardenPlots
+
+	return result, nil
+}
+
+// plotOf finds the plot with the enclosure id, or nil if there is none, relying
+// on the plots being ordered by enclosure id.
+func (fc *FencingCalculator) plotOf(enclosure int) *GardenPlot {
+	i := sort.Search(len(fc.gardenPlots), func(i int) bool {
+		return fc.gardenPlots[i].Enclosure >= enclosure
+	})
+	if i < len(fc.gardenPlots) && fc.gardenPlots[i].Enclosure == enclosure {
+		return fc.gardenPlots[i]
+	}
+
+	return nil
+}
+
+// regionCells lists the cells of the seed's region, with the seed first,
+// clearing them as it walks and then labelling them again.
+func (fc *FencingCalculator) regionCells(seed Point) []Point {
+	enclosure := fc.enclosures[seed.Row][seed.Col]
+	region := fc.ClearRegion(seed)
+	for _, point := range region {
+		fc.enclosures[point.Row][point.Col] = enclosure
+	}
+
+	return region
+}
+
+// UnmeasureCell takes back what MeasureCell added for the cell, apart from
+// the bounding box, which only shrinkBounds can tell has shrunk.
+func (fc *FencingCalculator) UnmeasureCell(plot *GardenPlot, row int, column int) {
+	plot.area -= 1
+	plot.rowSum -= row
+	plot.columnSum -= column
+
+	for _, direction := range orthogonalDirections {
+		if !fc.InEnclosure(row+direction.Row, column+direction.Col, plot.Enclosure) {
+			plot.perimiter -= 1
+		}
+	}
+
+	plot.sides -= fc.CountCorners(row, column, plot.Enclosure)
+}
+
+// shrinkBounds moves each side of the plot's bounding box in until it meets
+// one of the plot's cells.
+func (fc *FencingCalculator) shrinkBounds(plot *GardenPlot) {
+	bounds := &plot.bounds
+	inRow := func(row int) bool {
+		return slices.Contains(fc.enclosures[row][bounds.Left:bounds.Right+1], plot.Enclosure)
+	}
+	inColumn := func(column int) bool {
+		for row := bounds.Top; row <= bounds.Bottom; row++ {
+			if fc.enclosures[row][column] == plot.Enclosure {
+				return true
+			}
+		}
+		return false
+	}
+
+	for !inRow(bounds.Top) {
+		bounds.Top++
+	}
+	for !inRow(bounds.Bottom) {
+		bounds.Bottom--
+	}
+	for !inColumn(bounds.Left) {
+		bounds.Left++
+	}
+	for !inColumn(bounds.Right) {
+		bounds.Right--
+	}
+}
+
+// ClearRegion walks the seed's region through the calculator's connectivity,
+// unlabelling each cell as it goes, and returns the cells it cleared.
+func (fc *FencingCalculator) ClearRegion(seed Point) []Point {
+	enclosure := fc.enclosures[seed.Row][seed.Col]
+	fc.enclosures[seed.Row][seed.Col] = 0
+	region := []Point{seed}
+
+	for i := 0; i < len(region); i++ {
+		point := region[i]
+		for _, direction := range fc.connectivity {
+			neighbour := Point{Row: point.Row + direction.Row, Col: point.Col + direction.Col}
+			if fc.InEnclosure(neighbour.Row, neighbour.Col, enclosure) {
+				fc.enclosures[neighbour.Row][neighbour.Col] = 0
+				region = append(region, neighbour)
+			}
+		}
+	}
+
+	return region
+}
+
+type Replanting struct {
+	Row    int
+	Column int
+	Plant  rune
+}
+
+// ParseReplantings reads semicolon separated row,column,plant triples, such as
+// "0,0,A;4,2,🌻".
+func ParseReplantings(spec string) ([]Replanting, error) {
+	replantings := make([]Replanting, 0)
+	for _, entry := range strings.Split(spec, ";") {
+		fields := strings.Split(entry, ",")
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("invalid replanting %q, expected row,column,plant", entry)
+		}
+
+		row, err := strconv.Atoi(strings.TrimSpace(fields[0]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid replanting %q: %w", entry, err)
+		}
+
+		column, err := strconv.Atoi(strings.TrimSpace(fields[1]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid replanting %q: %w", entry, err)
+		}
+
+		plant := strings.TrimSpace(fields[2])
+		if utf8.RuneCountInString(plant) != 1 {
+			return nil, fmt.Errorf("invalid replanting %q: %q is not a single plant", entry, plant)
+		}
+
+		r, _ := utf8.DecodeRuneInString(plant)
+		err = ValidatePlant(r)
+		if err != nil {
+			return nil, fmt.Errorf("invalid replanting %q: %w", entry, err)
+		}
+
+		replantings = append(replantings, Replanting{Row: row, Column: column, Plant: r})
+	}
+
+	return replantings, nil
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+)
+
+// compareWithFullExamination checks the replanted calculator against one that
+// examined the same garden from scratch, cell by cell and region by region.
+func compareWithFullExamination(t *testing.T, fc *FencingCalculator, connectivity string) {
+	t.Helper()
+
+	lines := make([]string, len(fc.garden))
+	for row, plants := range fc.garden {
+		lines[row] = string(plants)
+	}
+	full := examineGarden(t, lines, connectivity)
+
+	if len(fc.gardenPlots) != len(full.gardenPlots) {
+		t.Fatalf("garden %q: expected %d regions, got %d", lines, len(full.gardenPlots), len(fc.gardenPlots))
+	}
+
+	plots := make(map[int]*GardenPlot)
+	for i, plot := range fc.gardenPlots {
+		plots[plot.Enclosure] = plot
+		if i > 0 && fc.gardenPlots[i-1].Enclosure >= plot.Enclosure {
+			t.Fatalf("garden %q: regions are not ordered by enclosure id", lines)
+		}
+	}
+	fullPlots := make(map[int]*GardenPlot)
+	for _, plot := range full.gardenPlots {
+		fullPlots[plot.Enclosure] = plot
+	}
+
+	// Enclosure ids differ between the two, so match regions up by the
+	// cells they cover.
+	matches := make(map[int]int)
+	matched := make(map[int]bool)
+	for row := range fc.enclosures {
+		for column, enclosure := range fc.enclosures[row] {
+			fullEnclosure := full.enclosures[row][column]
+			if match, ok := matches[enclosure]; ok {
+				if match != fullEnclosure {
+					t.Fatalf("garden %q: cell %d,%d is in a different region to others it shares one with", lines, row, column)
+				}
+				continue
+			}
+			if matched[fullEnclosure] {
+				t.Fatalf("garden %q: cell %d,%d is in the same region as cells it should not share one with", lines, row, column)
+			}
+
+			matches[enclosure] = fullEnclosure
+			matched[fullEnclosure] = true
+		}
+	}
+
+	for enclosure, fullEnclosure := range matches {
+		plot, fullPlot := plots[enclosure], fullPlots[fullEnclosure]
+		if plot == nil {
+			t.Fatalf("garden %q: enclosure %d has cells but no region", lines, enclosure)
+		}
+		if plot.Plant != fullPlot.Plant || plot.area != fullPlot.area || plot.perimiter != fullPlot.perimiter ||
+			plot.sides != fullPlot.sides || plot.bounds != fullPlot.bounds || plot.rowSum != fullPlot.rowSum ||
+			plot.columnSum != fullPlot.columnSum {
+			t.Fatalf("garden %q: region %+v should be %+v", lines, *plot, *fullPlot)
+		}
+
+		parent, fullParent := fc.Parent(plot), full.Parent(fullPlot)
+		if fc.HolePerimeter(plot) != full.HolePerimeter(fullPlot) || (parent == nil) != (fullParent == nil) ||
+			(parent != nil && matches[parent.Enclosure] != fullParent.Enclosure) {
+			t.Fatalf("garden %q: region %d is contained differently after replanting", lines, enclosure)
+		}
+	}
+}
+
+func TestReplantMatchesExamineGarden(t *testing.T) {
+	random := rand.New(rand.NewSource(43))
+	for _, connectivity := range []string{"4", "8", "0,2;1,0"} {
+		for i := 0; i < 150; i++ {
+			rows, columns := 1+random.Intn(9), 1+random.Intn(9)
+			plants := 2 + i%3
+			lines := make([]string, rows)
+			for row := range lines {
+				line := make([]byte, columns)
+				for column := range line {
+					line[column] = byte('A' + random.Intn(plants))
+				}
+				lines[row] = string(line)
+			}
+
+			fencingCalculator := examineGarden(t, lines, connectivity)
+			for step := 0; step < 30; step++ {
+				row, column, plant := random.Intn(rows), random.Intn(columns), rune('A'+random.Intn(plants))
+
+				price := fencingCalculator.CalculateTotalFencingPrice()
+				result, err := fencingCalculator.Replant(row, column, plant)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if change := fencingCalculator.CalculateTotalFencingPrice() - price; int(result.PriceChange(PerimeterPricing{})) != change {
+					t.Fatalf("replanting %d,%d with %q changed the price by %d, but the result says %v",
+						row, column, plant, change, result.PriceChange(PerimeterPricing{}))
+				}
+
+				// Leave the containment forest out of date now and then, so it
+				// is rebuilt after several replants as well as after one.
+				if step%3 != 0 {
+					compareWithFullExamination(t, fencingCalculator, connectivity)
+				}
+			}
+		}
+	}
+}
+
+func TestReplantOutsideGarden(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{"AB", "BA"}, "4")
+	for _, cell := range []Point{{Row: -1, Col: 0}, {Row: 0, Col: 2}, {Row: 2, Col: 1}} {
+		_, err := fencingCalculator.Replant(cell.Row, cell.Col, 'A')
+		if err == nil {
+			t.Errorf("expected replanting %d,%d to be rejected", cell.Row, cell.Col)
+		}
+	}
+}
+
+func TestReplantRelabelsTheSmallerRegion(t *testing.T) {
+	fencingCalculator := examineGarden(t, []string{"AAAABA", "CCCCCC"}, "4")
+
+	result, err := fencingCalculator.Replant(0, 4, 'A')
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The four A cells keep their id and the lone A on the right joins them.
+	for column, enclosure := range fencingCalculator.enclosures[0] {
+		if enclosure != 1 {
+			t.Fatalf("expected cell 0,%d to join region 1, got %d", column, enclosure)
+		}
+	}
+	if len(fencingCalculator.gardenPlots) != 2 || fencingCalculator.gardenPlots[0].area != 6 {
+		t.Fatalf("expected the A regions to merge into one of area 6, got %d regions", len(fencingCalculator.gardenPlots))
+	}
+	if len(result.Removed) != 4 || len(result.Added) != 2 {
+		t.Errorf("expected the A, B, lone A and C regions to be replaced by the merged A and C, got %d removed and %d added",
+			len(result.Removed), len(result.Added))
+	}
+	if change := result.PriceChange(PerimeterPricing{}); change != 6*14-4*10-1*4-1*4 {
+		t.Errorf("expected the price to change by %d, got %v", 6*14-4*10-1*4-1*4, change)
+	}
+}
+
+func BenchmarkReplant(b *testing.B) {
+	lines := make([]string, 1000)
+	for row := range lines {
+		lines[row] = strings.Repeat("A", 1000)
+	}
+	fencingCalculator := examineGarden(b, lines, "4")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, err := fencingCalculator.Replant(500, 500, 'B')
+		if err != nil {
+			b.Fatal(err)
+		}
+
+		_, err = fencingCalculator.Replant(500, 500, 'A')
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
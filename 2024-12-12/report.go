@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+)
+
+type PlotReport struct {
+	Enclosure      int         `json:"enclosure"`
+	Plant          string      `json:"plant"`
+	Area           int         `json:"area"`
+	Perimeter      int         `json:"perimeter"`
+	Sides          int         `json:"sides"`
+	BoundingBox    BoundingBox `json:"boundingBox"`
+	CentroidRow    float64     `json:"centroidRow"`
+	CentroidColumn float64     `json:"centroidColumn"`
+	Cells          int         `json:"cells"`
+	Price          int         `json:"price"`
+	BulkPrice      int         `json:"bulkPrice"`
+}
+
+func (gp *GardenPlot) Report() PlotReport {
+	centroidRow, centroidColumn := gp.Centroid()
+
+	return PlotReport{
+		Enclosure:      gp.Enclosure,
+		Plant:          string(gp.Plant),
+		Area:           gp.area,
+		Perimeter:      gp.perimiter,
+		Sides:          gp.sides,
+		BoundingBox:    gp.bounds,
+		CentroidRow:    centroidRow,
+		CentroidColumn: centroidColumn,
+		Cells:          gp.area,
+		Price:          gp.CalculateFencingPrice(),
+		BulkPrice:      gp.CalculateBulkFencingPrice(),
+	}
+}
+
+func (fc *FencingCalculator) Report() []PlotReport {
+	reports := make([]PlotReport, 0, len(fc.gardenPlots))
+	for _, plot := range fc.gardenPlots {
+		reports = append(reports, plot.Report())
+	}
+
+	return reports
+}
+
+func WriteGardenReport(w io.Writer, format string, reports []PlotReport) error {
+	switch format {
+	case "table":
+		return WriteTableReport(w, reports)
+	case "csv":
+		return WriteCsvReport(w, reports)
+	case "json":
+		return WriteJsonReport(w, reports)
+	default:
+		return fmt.Errorf("unknown report format %q, expected table, csv or json", format)
+	}
+}
+
+var reportHeader = []string{
+	"enclosure", "plant", "area", "perimeter", "sides",
+	"top", "left", "bottom", "right",
+	"centroid_row", "centroid_column", "cells", "price", "bulk_price",
+}
+
+func (pr PlotReport) Record() []string {
+	return []string{
+		strconv.Itoa(pr.Enclosure),
+		pr.Plant,
+		strconv.Itoa(pr.Area),
+		strconv.Itoa(pr.Perimeter),
+		strconv.Itoa(pr.Sides),
+		strconv.Itoa(pr.BoundingBox.Top),
+		strconv.Itoa(pr.BoundingBox.Left),
+		strconv.Itoa(pr.BoundingBox.Bottom),
+		strconv.Itoa(pr.BoundingBox.Right),
+		strconv.FormatFloat(pr.CentroidRow, 'f', 2, 64),
+		strconv.FormatFloat(pr.CentroidColumn, 'f', 2, 64),
+		strconv.Itoa(pr.Cells),
+		strconv.Itoa(pr.Price),
+		strconv.Itoa(pr.BulkPrice),
+	}
+}
+
+func WriteTableReport(w io.Writer, reports []PlotReport) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	writeRow := func(fields []string) error {
+		for _, field := range fields {
+			if _, err := fmt.Fprint(writer, field, "\t"); err != nil {
+				return err
+			}
+		}
+		_, err := fmt.Fprintln(writer)
+		return err
+	}
+
+	if err := writeRow(reportHeader); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if err := writeRow(report.Record()); err != nil {
+			return err
+		}
+	}
+
+	return writer.Flush()
+}
+
+func WriteCsvReport(w io.Writer, reports []PlotReport) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(reportHeader); err != nil {
+		return err
+	}
+	for _, report := range reports {
+		if err := writer.Write(report.Record()); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteJsonReport(w io.Writer, reports []PlotReport) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(reports)
+}
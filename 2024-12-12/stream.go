@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+)
+
+// Merge folds another plot's measurements into this one, for when two pieces
+// turn out to be the same region.
+func (gp *GardenPlot) Merge(other *GardenPlot) {
+	if other.area == 0 {
+		return
+	}
+
+	if gp.area == 0 {
+		gp.bounds = other.bounds
+	} else {
+		gp.bounds.Top = min(gp.bounds.Top, other.bounds.Top)
+		gp.bounds.Left = min(gp.bounds.Left, other.bounds.Left)
+		gp.bounds.Bottom = max(gp.bounds.Bottom, other.bounds.Bottom)
+		gp.bounds.Right = max(gp.bounds.Right, other.bounds.Right)
+	}
+
+	gp.area += other.area
+	gp.perimiter += other.perimiter
+	gp.sides += other.sides
+	gp.rowSum += other.rowSum
+	gp.columnSum += other.columnSum
+}
+
+// GardenStream labels a garden one row at a time with a union-find over the
+// regions still touching the last row read, so memory grows with the garden's
+// width and not its height. Regions are always orthogonally connected.
+type GardenStream struct {
+	emit func(plot *GardenPlot) error
+
+	row           int
+	plants        []rune
+	labels        []int
+	parents       []int
+	plots         []*GardenPlot
+	nextEnclosure int
+}
+
+// StreamGarden reads the garden once, allowing only trailing blank lines like
+// ReadGarden, and hands each region to emit as soon as no later row can
+// reach it. Regions are numbered in the order they are finished, which is not
+// the order ExamineGarden numbers them in.
+func StreamGarden(r io.Reader, emit func(plot *GardenPlot) error) error {
+	stream := &GardenStream{emit: emit}
+
+	blankLine := 0
+	lineNumber := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, bufio.MaxScanTokenSize), math.MaxInt32)
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if len(line) <= 0 {
+			if blankLine == 0 {
+				blankLine = lineNumber
+			}
+			continue
+		}
+
+		if blankLine != 0 {
+			return fmt.Errorf("garden has a blank row at line %d", blankLine)
+		}
+
+		plants, err := ParseGardenRow(line, stream.row)
+		if err != nil {
+			return err
+		}
+
+		err = stream.AddRow(plants)
+		if err != nil {
+			return err
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if stream.row == 0 {
+		return fmt.Errorf("garden is empty")
+	}
+
+	return stream.Finish()
+}
+
+func (gs *GardenStream) find(label int) int {
+	for gs.parents[label] != label {
+		gs.parents[label] = gs.parents[gs.parents[label]]
+		label = gs.parents[label]
+	}
+
+	return label
+}
+
+func (gs *GardenStream) union(a int, b int) int {
+	a, b = gs.find(a), gs.find(b)
+	if a == b {
+		return a
+	}
+
+	// Keep the older label as the root so the region's plot stays put.
+	if b < a {
+		a, b = b, a
+	}
+	gs.parents[b] = a
+	gs.plots[a].Merge(gs.plots[b])
+	gs.plots[b] = nil
+
+	return a
+}
+
+func (gs *GardenStream) AddRow(plants []rune) error {
+	if len(plants) == 0 {
+		return fmt.Errorf("garden is empty")
+	}
+	if gs.plants != nil && len(plants) != len(gs.plants) {
+		return fmt.Errorf("garden is ragged: row %d has %d plants but row 1 has %d", gs.row+1, len(plants), len(gs.plants))
+	}
+
+	labels := make([]int, len(plants))
+	for column, plant := range plants {
+		label := -1
+		perimiter := 4
+
+		if column > 0 && plants[column-1] == plant {
+			label = labels[column-1]
+			perimiter -= 2
+		}
+
+		if gs.plants != nil && gs.plants[column] == plant {
+			if label == -1 {
+				label = gs.find(gs.labels[column])
+			} else {
+				label = gs.union(label, gs.labels[column])
+			}
+			perimiter -= 2
+		}
+
+		if label == -1 {
+			label = len(gs.parents)
+			gs.parents = append(gs.parents, label)
+			gs.plots = append(gs.plots, &GardenPlot{Plant: plant})
+		}
+
+		labels[column] = label
+		plot := gs.plots[gs.find(label)]
+		plot.AddCell(Point{Row: gs.row, Col: column})
+		plot.perimiter += perimiter
+	}
+
+	gs.countCorners(gs.plants, gs.labels, plants, labels)
+
+	err := gs.retire(labels)
+	if err != nil {
+		return err
+	}
+
+	gs.plants = plants
+	gs.row++
+	return nil
+}
+
+// Finish counts the corners along the bottom of the garden and hands over the
+// regions in the last row.
+func (gs *GardenStream) Finish() error {
+	gs.countCorners(gs.plants, gs.labels, nil, nil)
+	return gs.retire(nil)
+}
+
+// countCorners looks at every 2x2 window straddling the line between the two
+// rows. A cell has a corner in the window when neither of its neighbours in
+// the window shares its plant, or both do and the diagonal one does not. With
+// orthogonal connectivity a shared plant next to a cell means a shared region,
+// so the plants alone decide it. A nil row lies outside the garden.
+func (gs *GardenStream) countCorners(above []rune, aboveLabels []int, below []rune, belowLabels []int) {
+	width := max(len(above), len(below))
+
+	plantAt := func(plants []rune, column int) (rune, bool) {
+		if plants == nil || column < 0 || column >= width {
+			return 0, false
+		}
+		return plants[column], true
+	}
+
+	same := func(plant rune, plants []rune, column int) bool {
+		other, ok := plantAt(plants, column)
+		return ok && other == plant
+	}
+
+	for vertex := 0; vertex <= width; vertex++ {
+		left, right := vertex-1, vertex
+		cells := []struct {
+			plants     []rune
+			labels     []int
+			column     int
+			vertical   []rune
+			horizontal int
+		}{
+			{above, aboveLabels, left, below, right},
+			{above, aboveLabels, right, below, left},
+			{below, belowLabels, left, above, right},
+			{below, belowLabels, right, above, left},
+		}
+
+		for _, cell := range cells {
+			plant, ok := plantAt(cell.plants, cell.column)
+			if !ok {
+				continue
+			}
+
+			vertical := same(plant, cell.vertical, cell.column)
+			horizontal := same(plant, cell.plants, cell.horizontal)
+			if (!vertical && !horizontal) || (vertical && horizontal && !same(plant, cell.vertical, cell.horizontal)) {
+				gs.plots[gs.find(cell.labels[cell.column])].sides++
+			}
+		}
+	}
+}
+
+// retire hands over every region from the previous row that did not carry on
+// into the new one, then renumbers the regions that did so the union-find only
+// ever holds one row's worth of labels.
+func (gs *GardenStream) retire(labels []int) error {
+	live := make([]int, len(gs.parents))
+	for label := range live {
+		live[label] = -1
+	}
+
+	numLive := 0
+	for _, label := range labels {
+		root := gs.find(label)
+		if live[root] == -1 {
+			live[root] = numLive
+			numLive++
+		}
+	}
+
+	for _, label := range gs.labels {
+		root := gs.find(label)
+		if live[root] != -1 || gs.plots[root] == nil {
+			continue
+		}
+
+		plot := gs.plots[root]
+		gs.plots[root] = nil
+		gs.nextEnclosure++
+		plot.Enclosure = gs.nextEnclosure
+
+		err := gs.emit(plot)
+		if err != nil {
+			return err
+		}
+	}
+
+	parents := make([]int, numLive)
+	plots := make([]*GardenPlot, numLive)
+	for root, label := range live {
+		if label != -1 {
+			parents[label] = label
+			plots[label] = gs.plots[root]
+		}
+	}
+	for column, label := range labels {
+		labels[column] = live[gs.find(label)]
+	}
+
+	gs.parents = parents
+	gs.plots = plots
+	gs.labels = labels
+	return nil
+}
+
+// StreamTotals streams the garden and adds up both fencing prices, writing a
+// CSV report row for each region as it is finished if w is not nil.
+func StreamTotals(r io.Reader, w io.Writer) (int, int, int, error) {
+	var writer *csv.Writer
+	if w != nil {
+		writer = csv.NewWriter(w)
+		if err := writer.Write(reportHeader); err != nil {
+			return 0, 0, 0, err
+		}
+	}
+
+	regions, totalPrice, totalBulkPrice := 0, 0, 0
+	err := StreamGarden(r, func(plot *GardenPlot) error {
+		regions++
+		totalPrice += plot.CalculateFencingPrice()
+		totalBulkPrice += plot.CalculateBulkFencingPrice()
+
+		if writer != nil {
+			return writer.Write(plot.Report().Record())
+		}
+		return nil
+	})
+
+	if writer != nil {
+		writer.Flush()
+		if err == nil {
+			err = writer.Error()
+		}
+	}
+
+	return regions, totalPrice, totalBulkPrice, err
+}
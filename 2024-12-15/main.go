@@ -0,0 +1,366 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+)
+
+func ParseInputFile(path string) ([][]byte, []byte) {
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+	defer file.Close()
+
+	var warehouse [][]byte
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if len(line) <= 0 {
+			break
+		}
+		warehouse = append(warehouse, []byte(line))
+	}
+
+	var instructions []byte
+	for scanner.Scan() {
+		line := scanner.Text()
+		instructions = append(instructions, []byte(line)...)
+	}
+
+	return warehouse, instructions
+}
+
+func main() {
+	var path string
+	flag.StringVar(&path, "path", "", "The path to the input file")
+
+	var debug bool
+	flag.BoolVar(&debug, "debug", false, "Specify whether or not to produce debug output")
+
+	var scale int
+	flag.IntVar(&scale, "scale", 1, "How many times wider everything in the warehouse is")
+
+	flag.Parse()
+
+	warehouse, instructions := ParseInputFile(path)
+	err := ValidateWarehouse(warehouse)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	warehouse, err = ResizeWarehouse(warehouse, scale)
+	if err != nil {
+		fmt.Println(err)
+		panic(0)
+	}
+
+	if debug {
+		fmt.Println("Initial state:")
+		PrintWarehouse(warehouse)
+	}
+
+	robot := FindRobot(warehouse)
+	for _, instruction := range instructions {
+		robot.Execute(warehouse, instruction)
+
+		if debug {
+			fmt.Printf("Move %q:\n", instruction)
+			PrintWarehouse(warehouse)
+		}
+	}
+
+	fmt.Println("Sum of all boxes' GPS coordinates: ", SumBoxGpsCoordinates(warehouse))
+}
+
+// ResizeWarehouse widens every tile by the scale. Walls and floor are repeated,
+// the robot keeps to the left of its tile and every box grows to the scale
+// times its width, so an O at scale 2 becomes [] and at scale 4 becomes [==].
+func ResizeWarehouse(warehouse [][]byte, scale int) ([][]byte, error) {
+	if scale < 1 {
+		return nil, fmt.Errorf("invalid scale %d, it must be at least 1", scale)
+	}
+	if scale == 1 {
+		return warehouse, nil
+	}
+
+	newWarehouse := make([][]byte, 0, len(warehouse))
+	for _, items := range warehouse {
+		newWarehouseItems := make([]byte, len(items)*scale)
+
+		for col, item := range items {
+			tile := newWarehouseItems[col*scale : (col+1)*scale]
+			for i := range tile {
+				tile[i] = item
+			}
+
+			switch item {
+			case '@':
+				for i := 1; i < scale; i++ {
+					tile[i] = '.'
+				}
+			case 'O':
+				tile[0] = '['
+				for i := 1; i < scale-1; i++ {
+					tile[i] = '='
+				}
+				tile[scale-1] = ']'
+			case '[':
+				for i := 1; i < scale; i++ {
+					tile[i] = '='
+				}
+			case ']':
+				for i := 0; i < scale-1; i++ {
+					tile[i] = '='
+				}
+			}
+		}
+
+		newWarehouse = append(newWarehouse, newWarehouseItems)
+	}
+
+	return newWarehouse, nil
+}
+
+// ValidateWarehouse checks there is exactly one robot and that every wide box
+// is a [ followed by any number of = and then a ], all on one row.
+func ValidateWarehouse(warehouse [][]byte) error {
+	robots := 0
+	for row, items := range warehouse {
+		boxStartCol := -1
+		for col, item := range items {
+			switch item {
+			case '#', '.', 'O':
+			case '@':
+				robots++
+			case '[':
+				if boxStartCol >= 0 {
+					return fmt.Errorf("box at row %d, column %d has no ] on its right", row, boxStartCol)
+				}
+				boxStartCol = col
+				continue
+			case '=':
+				if boxStartCol < 0 {
+					return fmt.Errorf("box at row %d, column %d has no [ on its left", row, col)
+				}
+				continue
+			case ']':
+				if boxStartCol < 0 {
+					return fmt.Errorf("box at row %d, column %d has no [ on its left", row, col)
+				}
+				boxStartCol = -1
+				continue
+			default:
+				return fmt.Errorf("unknown item %q at row %d, column %d", item, row, col)
+			}
+
+			if boxStartCol >= 0 {
+				return fmt.Errorf("box at row %d, column %d has no ] on its right", row, boxStartCol)
+			}
+		}
+
+		if boxStartCol >= 0 {
+			return fmt.Errorf("box at row %d, column %d has no ] on its right", row, boxStartCol)
+		}
+	}
+
+	if robots != 1 {
+		return fmt.Errorf("warehouse has %d robots, expected 1", robots)
+	}
+
+	return nil
+}
+
+func PrintWarehouse(warehouse [][]byte) {
+	for _, items := range warehouse {
+		fmt.Println(string(items[:]))
+	}
+	fmt.Print("\n")
+}
+
+func FindRobot(warehouse [][]byte) *Robot {
+	for row, items := range warehouse {
+		for col, item := range items {
+			if item == '@' {
+				return &Robot{
+					row: row,
+					col: col,
+				}
+			}
+		}
+	}
+
+	fmt.Println("There is no robot in the warehouse")
+	panic(0)
+}
+
+// ItemAt treats anywhere outside the warehouse as a wall, so warehouses that
+// are not walled in all the way round still keep the robot inside.
+func ItemAt(warehouse [][]byte, row int, col int) byte {
+	if row < 0 || row >= len(warehouse) || col < 0 || col >= len(warehouse[row]) {
+		return '#'
+	}
+
+	return warehouse[row][col]
+}
+
+// BoxAt finds the columns of the box covering the given tile, whether it is a
+// single O or a wide box such as [] or [==].
+func BoxAt(warehouse [][]byte, row int, col int) (int, int, bool) {
+	item := ItemAt(warehouse, row, col)
+	if item == 'O' {
+		return col, col, true
+	}
+	if item != '[' && item != '=' && item != ']' {
+		return 0, 0, false
+	}
+
+	leftSideCol := col
+	for warehouse[row][leftSideCol] != '[' {
+		leftSideCol--
+	}
+
+	rightSideCol := col
+	for warehouse[row][rightSideCol] != ']' {
+		rightSideCol++
+	}
+
+	return leftSideCol, rightSideCol, true
+}
+
+var directions = map[byte][2]int{
+	'^': {-1, 0},
+	'>': {0, 1},
+	'v': {1, 0},
+	'<': {0, -1},
+}
+
+type Robot struct {
+	row int
+	col int
+}
+
+func (r *Robot) Execute(warehouse [][]byte, instruction byte) {
+	direction, ok := directions[instruction]
+	if !ok {
+		return
+	}
+
+	nextRow, nextCol := r.row+direction[0], r.col+direction[1]
+	item := ItemAt(warehouse, nextRow, nextCol)
+	if item == '#' {
+		return
+	}
+
+	if item != '.' {
+		leftSideCol, rightSideCol, isBox := BoxAt(warehouse, nextRow, nextCol)
+		if !isBox {
+			return
+		}
+
+		boxes, canPush := CollectBoxes(warehouse, Box{Row: nextRow, LeftSideCol: leftSideCol, RightSideCol: rightSideCol}, direction)
+		if !canPush {
+			return
+		}
+
+		MoveBoxes(warehouse, boxes, direction)
+	}
+
+	warehouse[nextRow][nextCol] = '@'
+	warehouse[r.row][r.col] = '.'
+	r.row, r.col = nextRow, nextCol
+}
+
+type Box struct {
+	Row          int
+	LeftSideCol  int
+	RightSideCol int
+}
+
+// boxesInTheWay lists the boxes that the box runs into when it moves one tile
+// in the direction, and whether a wall stops it. Moving sideways only the tile
+// past its end matters, but moving up or down every tile above or below it
+// does.
+func boxesInTheWay(warehouse [][]byte, box Box, direction [2]int) ([]Box, bool) {
+	nextRow := box.Row + direction[0]
+	fromCol, toCol := box.LeftSideCol, box.RightSideCol
+	if direction[1] < 0 {
+		fromCol, toCol = box.LeftSideCol-1, box.LeftSideCol-1
+	} else if direction[1] > 0 {
+		fromCol, toCol = box.RightSideCol+1, box.RightSideCol+1
+	}
+
+	boxes := make([]Box, 0)
+	for col := fromCol; col <= toCol; col++ {
+		if ItemAt(warehouse, nextRow, col) == '#' {
+			return nil, true
+		}
+
+		leftSideCol, rightSideCol, isBox := BoxAt(warehouse, nextRow, col)
+		if isBox {
+			boxes = append(boxes, Box{Row: nextRow, LeftSideCol: leftSideCol, RightSideCol: rightSideCol})
+			col = rightSideCol
+		}
+	}
+
+	return boxes, false
+}
+
+// CollectBoxes finds every box that moves when the first one is pushed, going
+// breadth first and visiting each box once, however many boxes rest on it. It
+// returns false as soon as any of them would run into a wall.
+func CollectBoxes(warehouse [][]byte, first Box, direction [2]int) ([]Box, bool) {
+	visited := map[Box]bool{first: true}
+	boxes := []Box{first}
+
+	for i := 0; i < len(boxes); i++ {
+		boxesAhead, blocked := boxesInTheWay(warehouse, boxes[i], direction)
+		if blocked {
+			return nil, false
+		}
+
+		for _, box := range boxesAhead {
+			if !visited[box] {
+				visited[box] = true
+				boxes = append(boxes, box)
+			}
+		}
+	}
+
+	return boxes, true
+}
+
+// MoveBoxes lifts every box off the floor before putting any of them down one
+// tile along, so boxes moving into each other's old tiles cannot overwrite one
+// another whatever order they are in.
+func MoveBoxes(warehouse [][]byte, boxes []Box, direction [2]int) {
+	contents := make([][]byte, len(boxes))
+	for i, box := range boxes {
+		contents[i] = make([]byte, box.RightSideCol-box.LeftSideCol+1)
+		copy(contents[i], warehouse[box.Row][box.LeftSideCol:box.RightSideCol+1])
+		for col := box.LeftSideCol; col <= box.RightSideCol; col++ {
+			warehouse[box.Row][col] = '.'
+		}
+	}
+
+	for i, box := range boxes {
+		copy(warehouse[box.Row+direction[0]][box.LeftSideCol+direction[1]:], contents[i])
+	}
+}
+
+func SumBoxGpsCoordinates(warehouse [][]byte) int {
+	runningTotal := 0
+	for row, items := range warehouse {
+		for col, item := range items {
+			if item == 'O' || item == '[' {
+				runningTotal += 100*row + col
+			}
+		}
+	}
+
+	return runningTotal
+}
@@ -0,0 +1,401 @@
+package main
+
+import (
+	"bytes"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// The recursive pushes that CollectBoxes and MoveBoxes replaced, kept to check
+// the new ones against.
+
+func recursiveBoxesInTheWay(warehouse [][]byte, row int, leftSideCol int, rightSideCol int, direction [2]int) ([][2]int, bool) {
+	boxes, blocked := boxesInTheWay(warehouse, Box{Row: row, LeftSideCol: leftSideCol, RightSideCol: rightSideCol}, direction)
+	columns := make([][2]int, 0, len(boxes))
+	for _, box := range boxes {
+		columns = append(columns, [2]int{box.LeftSideCol, box.RightSideCol})
+	}
+
+	return columns, blocked
+}
+
+func recursiveCanPushBox(warehouse [][]byte, row int, leftSideCol int, rightSideCol int, direction [2]int) bool {
+	boxes, blocked := recursiveBoxesInTheWay(warehouse, row, leftSideCol, rightSideCol, direction)
+	if blocked {
+		return false
+	}
+
+	for _, box := range boxes {
+		if !recursiveCanPushBox(warehouse, row+direction[0], box[0], box[1], direction) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func recursivePushBox(warehouse [][]byte, row int, leftSideCol int, rightSideCol int, direction [2]int) {
+	boxes, _ := recursiveBoxesInTheWay(warehouse, row, leftSideCol, rightSideCol, direction)
+	for _, box := range boxes {
+		recursivePushBox(warehouse, row+direction[0], box[0], box[1], direction)
+	}
+
+	box := make([]byte, rightSideCol-leftSideCol+1)
+	copy(box, warehouse[row][leftSideCol:rightSideCol+1])
+	for col := leftSideCol; col <= rightSideCol; col++ {
+		warehouse[row][col] = '.'
+	}
+	copy(warehouse[row+direction[0]][leftSideCol+direction[1]:], box)
+}
+
+func recursiveExecute(r *Robot, warehouse [][]byte, instruction byte) {
+	direction, ok := directions[instruction]
+	if !ok {
+		return
+	}
+
+	nextRow, nextCol := r.row+direction[0], r.col+direction[1]
+	item := ItemAt(warehouse, nextRow, nextCol)
+	if item == '#' {
+		return
+	}
+
+	if item != '.' {
+		leftSideCol, rightSideCol, isBox := BoxAt(warehouse, nextRow, nextCol)
+		if !isBox || !recursiveCanPushBox(warehouse, nextRow, leftSideCol, rightSideCol, direction) {
+			return
+		}
+
+		recursivePushBox(warehouse, nextRow, leftSideCol, rightSideCol, direction)
+	}
+
+	warehouse[nextRow][nextCol] = '@'
+	warehouse[r.row][r.col] = '.'
+	r.row, r.col = nextRow, nextCol
+}
+
+func cloneWarehouse(warehouse [][]byte) [][]byte {
+	clone := make([][]byte, len(warehouse))
+	for row, items := range warehouse {
+		clone[row] = bytes.Clone(items)
+	}
+
+	return clone
+}
+
+func randomWarehouse(random *rand.Rand, rows int, cols int) [][]byte {
+	warehouse := make([][]byte, rows)
+	for row := range warehouse {
+		warehouse[row] = make([]byte, cols)
+		for col := range warehouse[row] {
+			switch roll := random.Intn(10); {
+			case roll < 1:
+				warehouse[row][col] = '#'
+			case roll < 6:
+				warehouse[row][col] = 'O'
+			default:
+				warehouse[row][col] = '.'
+			}
+		}
+	}
+	warehouse[random.Intn(rows)][random.Intn(cols)] = '@'
+
+	return warehouse
+}
+
+func TestExecuteMatchesRecursivePushes(t *testing.T) {
+	random := rand.New(rand.NewSource(50))
+	instructions := []byte("^>v<")
+
+	for i := 0; i < 300; i++ {
+		for _, scale := range []int{1, 2, 3, 4} {
+			warehouse, err := ResizeWarehouse(randomWarehouse(random, 2+random.Intn(10), 2+random.Intn(8)), scale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			expected := cloneWarehouse(warehouse)
+
+			robot, expectedRobot := FindRobot(warehouse), FindRobot(expected)
+			for move := 0; move < 100; move++ {
+				instruction := instructions[random.Intn(len(instructions))]
+				robot.Execute(warehouse, instruction)
+				recursiveExecute(expectedRobot, expected, instruction)
+
+				if !bytes.Equal(bytes.Join(warehouse, []byte("\n")), bytes.Join(expected, []byte("\n"))) {
+					t.Fatalf("scale %d, move %d %q: warehouse is\n%s\nexpected\n%s", scale, move, instruction,
+						bytes.Join(warehouse, []byte("\n")), bytes.Join(expected, []byte("\n")))
+				}
+			}
+
+			err = ValidateWarehouse(warehouse)
+			if err != nil {
+				t.Fatalf("scale %d: %v", scale, err)
+			}
+		}
+	}
+}
+
+// randomMixedWarehouse fills each row with walls, floor and boxes from one to
+// four tiles wide, so boxes of different widths rest against each other.
+func randomMixedWarehouse(random *rand.Rand, rows int, cols int) [][]byte {
+	boxes := [][]byte{[]byte("O"), []byte("[]"), []byte("[=]"), []byte("[==]")}
+
+	warehouse := make([][]byte, rows)
+	for row := range warehouse {
+		items := make([]byte, 0, cols)
+		for len(items) < cols {
+			switch roll := random.Intn(10); {
+			case roll < 1:
+				items = append(items, '#')
+			case roll < 6:
+				box := boxes[random.Intn(len(boxes))]
+				if len(items)+len(box) <= cols {
+					items = append(items, box...)
+				}
+			default:
+				items = append(items, '.')
+			}
+		}
+		warehouse[row] = items
+	}
+
+	// The robot only ever replaces floor or a wall, so every box stays whole.
+	for {
+		row, col := random.Intn(rows), random.Intn(cols)
+		if warehouse[row][col] == '.' || warehouse[row][col] == '#' {
+			warehouse[row][col] = '@'
+			return warehouse
+		}
+	}
+}
+
+func TestExecuteMatchesRecursivePushesWithMixedBoxes(t *testing.T) {
+	random := rand.New(rand.NewSource(49))
+	instructions := []byte("^>v<")
+
+	for i := 0; i < 300; i++ {
+		rows, cols := 2+random.Intn(10), 4+random.Intn(12)
+		mixed := randomMixedWarehouse(random, rows, cols)
+
+		for _, scale := range []int{1, 2} {
+			warehouse, err := ResizeWarehouse(cloneWarehouse(mixed), scale)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = ValidateWarehouse(warehouse)
+			if err != nil {
+				t.Fatalf("scale %d: %v in\n%s", scale, err, bytes.Join(warehouse, []byte("\n")))
+			}
+			expected := cloneWarehouse(warehouse)
+
+			robot, expectedRobot := FindRobot(warehouse), FindRobot(expected)
+			for move := 0; move < 100; move++ {
+				instruction := instructions[random.Intn(len(instructions))]
+				robot.Execute(warehouse, instruction)
+				recursiveExecute(expectedRobot, expected, instruction)
+
+				if !bytes.Equal(bytes.Join(warehouse, []byte("\n")), bytes.Join(expected, []byte("\n"))) {
+					t.Fatalf("scale %d, move %d %q: warehouse is\n%s\nexpected\n%s", scale, move, instruction,
+						bytes.Join(warehouse, []byte("\n")), bytes.Join(expected, []byte("\n")))
+				}
+			}
+
+			err = ValidateWarehouse(warehouse)
+			if err != nil {
+				t.Fatalf("scale %d: %v", scale, err)
+			}
+		}
+	}
+}
+
+func TestExecuteWideBoxesFromInput(t *testing.T) {
+	tests := []struct {
+		name         string
+		warehouse    []string
+		instructions string
+		expected     []string
+	}{
+		{
+			// Pushed from under its second tile the [==] moves all four
+			// tiles, and pushing again leaves it against the wall.
+			"up",
+			[]string{"#########", "#.......#", "#.[==]..#", "#..@....#", "#########"},
+			"^^",
+			[]string{"#########", "#.[==]..#", "#..@....#", "#.......#", "#########"},
+		},
+		{
+			// A row of boxes of every width moves together until the last one
+			// reaches the wall.
+			"right",
+			[]string{"###########", "#@O[][==].#", "###########"},
+			">>",
+			[]string{"###########", "#.@O[][==]#", "###########"},
+		},
+		{
+			// A [==] resting across a [] and a [=] pushes both of them down.
+			"down onto narrower boxes",
+			[]string{"##########", "#..@.....#", "#.[==]...#", "#[][=]...#", "#........#", "##########"},
+			"v",
+			[]string{"##########", "#........#", "#..@.....#", "#.[==]...#", "#[][=]...#", "##########"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warehouse := make([][]byte, len(test.warehouse))
+			for row, line := range test.warehouse {
+				warehouse[row] = []byte(line)
+			}
+
+			err := ValidateWarehouse(warehouse)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			robot := FindRobot(warehouse)
+			for _, instruction := range []byte(test.instructions) {
+				robot.Execute(warehouse, instruction)
+			}
+
+			actual := string(bytes.Join(warehouse, []byte("\n")))
+			expected := strings.Join(test.expected, "\n")
+			if actual != expected {
+				t.Errorf("warehouse is\n%s\nexpected\n%s", actual, expected)
+			}
+		})
+	}
+}
+
+func TestValidateWarehouse(t *testing.T) {
+	tests := []struct {
+		name      string
+		warehouse []string
+		err       string
+	}{
+		{"every width", []string{"#@.O[][=][==]#"}, ""},
+		{"box without ]", []string{"@.[==.#"}, "box at row 0, column 2 has no ] on its right"},
+		{"box at the end of the row", []string{"@.[=", "...."}, "box at row 0, column 2 has no ] on its right"},
+		{"box inside a box", []string{"@[[]]"}, "box at row 0, column 1 has no ] on its right"},
+		{"robot inside a box", []string{".[@]"}, "box at row 0, column 1 has no ] on its right"},
+		{"stray =", []string{"@.=]"}, "box at row 0, column 2 has no [ on its left"},
+		{"stray ]", []string{"@", "[]]"}, "box at row 1, column 2 has no [ on its left"},
+		{"unknown item", []string{"@.x"}, "unknown item 'x' at row 0, column 2"},
+		{"no robot", []string{"#.O#"}, "warehouse has 0 robots, expected 1"},
+		{"two robots", []string{"@.[]@"}, "warehouse has 2 robots, expected 1"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			warehouse := make([][]byte, len(test.warehouse))
+			for row, line := range test.warehouse {
+				warehouse[row] = []byte(line)
+			}
+
+			err := ValidateWarehouse(warehouse)
+			if test.err == "" {
+				if err != nil {
+					t.Errorf("expected the warehouse to be valid, got %v", err)
+				}
+			} else if err == nil || err.Error() != test.err {
+				t.Errorf("expected %q, got %v", test.err, err)
+			}
+		})
+	}
+}
+
+// boxPyramid stacks wide boxes in rows of one, two, three and so on, each row
+// offset by half a box from the one below, with the robot underneath. Every
+// box but those on the edges rests on two boxes, so walking the pyramid box by
+// box from each box above it takes exponentially many steps.
+func boxPyramid(levels int) ([][]byte, Box) {
+	rows, cols := levels+3, 2*levels+4
+	warehouse := make([][]byte, rows)
+	for row := range warehouse {
+		warehouse[row] = bytes.Repeat([]byte("."), cols)
+	}
+
+	apexRow, apexCol := rows-2, levels+1
+	for level := 0; level < levels; level++ {
+		for i := 0; i <= level; i++ {
+			col := apexCol - level + 2*i
+			warehouse[apexRow-level][col] = '['
+			warehouse[apexRow-level][col+1] = ']'
+		}
+	}
+	warehouse[rows-1][apexCol] = '@'
+
+	return warehouse, Box{Row: apexRow, LeftSideCol: apexCol, RightSideCol: apexCol + 1}
+}
+
+func TestPushPyramid(t *testing.T) {
+	warehouse, apex := boxPyramid(5)
+	up := directions['^']
+
+	boxes, canPush := CollectBoxes(warehouse, apex, up)
+	if !canPush || len(boxes) != 15 {
+		t.Fatalf("expected to push all 15 boxes, got %d and %v", len(boxes), canPush)
+	}
+
+	expected := cloneWarehouse(warehouse)
+	MoveBoxes(warehouse, boxes, up)
+	recursivePushBox(expected, apex.Row, apex.LeftSideCol, apex.RightSideCol, up)
+	if !bytes.Equal(bytes.Join(warehouse, nil), bytes.Join(expected, nil)) {
+		t.Errorf("pyramid moved to\n%s\nexpected\n%s", bytes.Join(warehouse, []byte("\n")), bytes.Join(expected, []byte("\n")))
+	}
+
+	// One more push takes the top row to the edge of the warehouse, and then
+	// nothing moves.
+	apex.Row--
+	boxes, canPush = CollectBoxes(warehouse, apex, up)
+	if !canPush {
+		t.Fatal("expected the pyramid to move up to the edge of the warehouse")
+	}
+	MoveBoxes(warehouse, boxes, up)
+
+	apex.Row--
+	boxes, canPush = CollectBoxes(warehouse, apex, up)
+	if canPush || boxes != nil {
+		t.Errorf("expected the pyramid to be stuck against the edge, got %d boxes", len(boxes))
+	}
+}
+
+func BenchmarkPushPyramid(b *testing.B) {
+	up := directions['^']
+	for _, levels := range []int{10, 100, 1000} {
+		b.Run(strconv.Itoa(levels)+"-levels", func(b *testing.B) {
+			template, apex := boxPyramid(levels)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				warehouse := cloneWarehouse(template)
+				b.StartTimer()
+
+				boxes, canPush := CollectBoxes(warehouse, apex, up)
+				if !canPush {
+					b.Fatal("the pyramid cannot be pushed")
+				}
+				MoveBoxes(warehouse, boxes, up)
+			}
+		})
+	}
+}
+
+func BenchmarkRecursivePushPyramid(b *testing.B) {
+	up := directions['^']
+	for _, levels := range []int{10, 15, 20} {
+		b.Run(strconv.Itoa(levels)+"-levels", func(b *testing.B) {
+			template, apex := boxPyramid(levels)
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				warehouse := cloneWarehouse(template)
+				b.StartTimer()
+
+				if !recursiveCanPushBox(warehouse, apex.Row, apex.LeftSideCol, apex.RightSideCol, up) {
+					b.Fatal("the pyramid cannot be pushed")
+				}
+				recursivePushBox(warehouse, apex.Row, apex.LeftSideCol, apex.RightSideCol, up)
+			}
+		})
+	}
+}